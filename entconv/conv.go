@@ -4,18 +4,18 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
-	"go/ast"
 	"go/format"
-	"go/parser"
-	"go/token"
 	"path"
+	"path/filepath"
 	"runtime/debug"
 	"slices"
 	"strings"
+	"text/template"
 
 	"entgo.io/ent/entc"
 	"entgo.io/ent/entc/gen"
 	"entgo.io/ent/schema/field"
+	"github.com/go-sphere/entc-extensions/entconv/internal/converter"
 	"github.com/go-sphere/entc-extensions/entconv/internal/generator"
 	"github.com/go-sphere/entc-extensions/entconv/internal/pkgutil"
 	"github.com/go-sphere/entc-extensions/entproto"
@@ -23,17 +23,117 @@ import (
 )
 
 type Options struct {
-	SchemaPath         string
-	EntPackagePath     string
-	IDType             string
-	ProtoFile          string
+	SchemaPath     string
+	EntPackagePath string
+	// IDType is the ID type of the schemas without an id field: int, int64,
+	// uint, uint64 or string. The ID types of the other schemas, e.g. UUIDs,
+	// are read from their id field in the graph. An Extension uses the IDType
+	// of gen.Config instead.
+	IDType string
+	// ProtoFile is a .pb.go file or a directory of the Go package generated
+	// from the proto files. The whole package is loaded, so messages may be
+	// spread over several files.
+	ProtoFile string
+	// ProtoPaths lists further .pb.go files or directories to load messages from.
+	ProtoPaths         []string
 	ConvPackage        string
 	ProtoPackagePath   string
 	ProtoAlias         string
 	OutDir             string
 	MissingProtoPolicy MissingProtoPolicy
-	WarningHandler     func(error)
-}
+	// CoveragePolicy decides what the fields left out by the converters do,
+	// see CoverageReport. TypeCoveragePolicies overrides it per ent type.
+	CoveragePolicy       CoveragePolicy
+	TypeCoveragePolicies map[string]CoveragePolicy
+	WarningHandler       func(error)
+	// MaxEdgeDepth is the number of edge levels converted below an entity by the
+	// generated converters. Zero means DefaultMaxEdgeDepth; a negative value
+	// disables edge conversion.
+	MaxEdgeDepth int
+	// MessageNames maps ent type names to the proto messages converted to and
+	// from them. The first message is the message of the type; further ones are
+	// subset messages holding some of its fields, e.g. a UserSummary next to
+	// User, converted by ToProtoUserSummary and ToEntUserSummary.
+	MessageNames map[string][]string
+	// MessagePrefix and MessageSuffix name the message of the types without an
+	// entry in MessageNames or an entproto.MessageName, e.g. the suffix "DTO"
+	// matches the type User with the message UserDTO.
+	MessagePrefix string
+	MessageSuffix string
+	// ProtoPackages configures the Go packages of the proto packages of the
+	// schema, set with entproto.PackageName. Proto packages without an entry
+	// are resolved from the loaded messages.
+	ProtoPackages []ProtoPackage
+	// Strict makes the generated converters return a *ConversionError, declared
+	// in the generated package, for enum values without a counterpart and for
+	// integers overflowing the type they are converted to. By default such
+	// values are mapped to the zero value or truncated.
+	Strict bool
+	// FieldConverters override the conversion of single fields with user
+	// functions, see WithFieldConverter.
+	FieldConverters []FieldConverter
+	// TimeFormat is the pb representation of time fields, see WithTimeFormat.
+	// FieldTimeFormats overrides it for single fields, keyed by ent type and
+	// field name, see WithFieldTimeFormat.
+	TimeFormat       TimeFormat
+	FieldTimeFormats map[string]map[string]TimeFormat
+	// UnixZeroTime makes the converters decode the Unix time 0 to the zero
+	// time, see WithUnixZeroTime.
+	UnixZeroTime bool
+	// CloneMessages makes the generated converters deep-copy the values of
+	// external message fields, annotated with entproto.MessageField, with
+	// proto.Clone, so that the ent and pb values do not share them. It is on by
+	// default.
+	CloneMessages bool
+	// Tests makes GenerateConverterFile write a <type>_test.go file next to the
+	// converters of each type, with a round-trip test over random entities and
+	// a fuzz target over the pb message, see WithTests.
+	Tests bool
+	// AllowSensitive lists, per ent type, the Sensitive fields converted by
+	// ToProto. Other Sensitive fields are redacted: ToProto leaves them unset,
+	// and only the generated ToProto<Type>Unredacted converts them.
+	AllowSensitive map[string][]string
+	// ProtoExtension is the entproto extension whose adapter an Extension
+	// shares, see WithProtoExtension.
+	ProtoExtension *entproto.Extension
+	// Templates are parsed after the built-in templates of the generated code,
+	// overriding or adding named templates, see WithTemplates.
+	Templates []TemplateFiles
+	// TemplateFuncs are added to the functions of the templates, see
+	// WithTemplateFuncs.
+	TemplateFuncs template.FuncMap
+	// Parallelism is the maximum number of converter files rendered
+	// concurrently. Values below one mean runtime.GOMAXPROCS(0). The output
+	// does not depend on it.
+	Parallelism int
+	// Check makes GenerateConverterFile compare the generated converters with
+	// the files on disk instead of writing them, and return an *OutOfDateError
+	// when they differ, e.g. to verify in CI that they were regenerated.
+	Check bool
+}
+
+// ProtoPackage configures the Go package generated from a proto package.
+type ProtoPackage struct {
+	// Name is the proto package, e.g. "acme.user.v1".
+	Name string
+	// GoPackage is the import path of the Go package generated from it. By
+	// default it is the package its messages were loaded from; when messages of
+	// several packages share a name, the go_package option of the proto file
+	// and then ProtoPackagePath decide.
+	GoPackage string
+	// Alias is the import alias of GoPackage in the generated code. It defaults
+	// to ProtoAlias for ProtoPackagePath, and otherwise to the last element of
+	// Name, prefixed by the previous one for versions, e.g. userv1.
+	Alias string
+	// OutDir, when set, is the directory GenerateConverterFile writes the
+	// converters of the types of the package to, in a Go package named after
+	// its base name. Edges to types written to another directory are not
+	// converted.
+	OutDir string
+}
+
+// DefaultMaxEdgeDepth is the edge depth used when Options.MaxEdgeDepth is zero.
+const DefaultMaxEdgeDepth = 3
 
 type MissingProtoPolicy string
 
@@ -55,10 +155,27 @@ func (e *MissingProtoMessagesError) Is(target error) bool {
 	return errors.As(target, &t)
 }
 
+// OutOfDateError is returned in check mode when the converter files on disk
+// differ from the generated ones.
+type OutOfDateError struct {
+	// Files are the converter files that would be written or removed.
+	Files []string
+	// Diff is a unified diff from the files on disk to the generated ones.
+	Diff string
+}
+
+func (e *OutOfDateError) Error() string {
+	return fmt.Sprintf("converters are out of date: %s\n%s", strings.Join(e.Files, ", "), e.Diff)
+}
+
 type Option func(*Options)
 
 func DefaultOptions() *Options {
-	modulePath := currentModulePath()
+	return defaultOptions(currentModulePath())
+}
+
+// defaultOptions returns the default options of a project with the module path modulePath.
+func defaultOptions(modulePath string) *Options {
 	return &Options{
 		IDType:             "int64",
 		SchemaPath:         "./internal/pkg/database/schema",
@@ -69,11 +186,18 @@ func DefaultOptions() *Options {
 		ProtoAlias:         "entpb",
 		OutDir:             "./internal/pkg/render/entmap",
 		MissingProtoPolicy: MissingProtoPolicyStrict,
+		CloneMessages:      true,
 	}
 }
 
 func NewOptions(opts ...Option) *Options {
-	o := DefaultOptions()
+	return newOptions(currentModulePath(), opts)
+}
+
+// newOptions applies opts to the default options of a project with the module
+// path modulePath.
+func newOptions(modulePath string, opts []Option) *Options {
+	o := defaultOptions(modulePath)
 	for _, opt := range opts {
 		if opt != nil {
 			opt(o)
@@ -106,6 +230,12 @@ func WithProtoFile(v string) Option {
 	}
 }
 
+func WithProtoPaths(v ...string) Option {
+	return func(o *Options) {
+		o.ProtoPaths = append(o.ProtoPaths, v...)
+	}
+}
+
 func WithConvPackage(v string) Option {
 	return func(o *Options) {
 		o.ConvPackage = v
@@ -136,6 +266,78 @@ func WithMissingProtoPolicy(v MissingProtoPolicy) Option {
 	}
 }
 
+func WithMaxEdgeDepth(v int) Option {
+	return func(o *Options) {
+		o.MaxEdgeDepth = v
+	}
+}
+
+func WithStrict(v bool) Option {
+	return func(o *Options) {
+		o.Strict = v
+	}
+}
+
+// WithCloneMessages sets whether the generated converters deep-copy the values
+// of external message fields. Without cloning, they assign them directly and
+// the ent and pb values share them, so mutating one mutates the other.
+func WithCloneMessages(v bool) Option {
+	return func(o *Options) {
+		o.CloneMessages = v
+	}
+}
+
+// WithTests sets whether round-trip tests and fuzz targets are generated for
+// the converters of each type.
+func WithTests(v bool) Option {
+	return func(o *Options) {
+		o.Tests = v
+	}
+}
+
+func WithMessageNames(typeName string, messages ...string) Option {
+	return func(o *Options) {
+		if o.MessageNames == nil {
+			o.MessageNames = make(map[string][]string)
+		}
+		o.MessageNames[typeName] = append(o.MessageNames[typeName], messages...)
+	}
+}
+
+func WithMessageNameRule(prefix, suffix string) Option {
+	return func(o *Options) {
+		o.MessagePrefix = prefix
+		o.MessageSuffix = suffix
+	}
+}
+
+func WithAllowSensitive(typeName string, fields ...string) Option {
+	return func(o *Options) {
+		if o.AllowSensitive == nil {
+			o.AllowSensitive = make(map[string][]string)
+		}
+		o.AllowSensitive[typeName] = append(o.AllowSensitive[typeName], fields...)
+	}
+}
+
+func WithProtoPackages(v ...ProtoPackage) Option {
+	return func(o *Options) {
+		o.ProtoPackages = append(o.ProtoPackages, v...)
+	}
+}
+
+func WithParallelism(v int) Option {
+	return func(o *Options) {
+		o.Parallelism = v
+	}
+}
+
+func WithCheck(v bool) Option {
+	return func(o *Options) {
+		o.Check = v
+	}
+}
+
 func WithWarningHandler(h func(error)) Option {
 	return func(o *Options) {
 		o.WarningHandler = h
@@ -183,7 +385,57 @@ func GenerateConverterFile(opts *Options) error {
 	if err != nil {
 		return err
 	}
-	return cg.GenerateAll(opts.OutDir)
+	return writeConverterFiles(cg, opts)
+}
+
+// writeConverterFiles writes the converters of cg to opts.OutDir, and to the
+// output directories of their proto packages, removing the stale converter
+// files of the directories. In check mode, it only compares them with the
+// files on disk.
+func writeConverterFiles(cg *generator.Generator, opts *Options) error {
+	// Group the types by output directory, in the order of the graph.
+	var dirs []string
+	byDir := make(map[string][]generator.TypeInfo)
+	for _, t := range cg.Types {
+		dir := opts.OutDir
+		if pkg := protoPackageConfig(opts, t.ProtoPackage); pkg.OutDir != "" {
+			dir = pkg.OutDir
+		}
+		if _, ok := byDir[dir]; !ok {
+			dirs = append(dirs, dir)
+		}
+		byDir[dir] = append(byDir[dir], t)
+	}
+	var outOfDate OutOfDateError
+	for _, dir := range dirs {
+		pkg := opts.ConvPackage
+		if dir != opts.OutDir {
+			pkg = filepath.Base(dir)
+		}
+		dg := cg.ForTypes(pkg, byDir[dir])
+		if !opts.Check {
+			if err := dg.GenerateAll(dir); err != nil {
+				return fmt.Errorf("generating %s: %w", dir, err)
+			}
+			continue
+		}
+		files, err := dg.RenderAll(dir)
+		if err != nil {
+			return fmt.Errorf("generating %s: %w", dir, err)
+		}
+		diffs, err := generator.DiffDir(dir, files)
+		if err != nil {
+			return fmt.Errorf("checking %s: %w", dir, err)
+		}
+		for _, d := range diffs {
+			outOfDate.Files = append(outOfDate.Files, d.Path)
+			outOfDate.Diff += d.Diff
+		}
+	}
+	if len(outOfDate.Files) > 0 {
+		return &outOfDate
+	}
+	return nil
 }
 
 func GenerateConverterFileWithOptions(opts ...Option) error {
@@ -200,18 +452,35 @@ func prepareGenerator(opts *Options) (*generator.Generator, error) {
 		return nil, fmt.Errorf("resolving ent package: %w", err)
 	}
 
-	idType := parseIDType(opts.IDType)
+	idType, err := parseIDType(opts.IDType)
+	if err != nil {
+		return nil, err
+	}
 	g, err := loadEntGraph(opts.SchemaPath, entPkg, idType)
 	if err != nil {
 		return nil, fmt.Errorf("loading ent graph: %w", err)
 	}
 
-	protoTypes, err := parseProtoFile(opts.ProtoFile)
+	adapter, err := loadAdapter(g)
 	if err != nil {
-		return nil, fmt.Errorf("parsing proto file: %w", err)
+		return nil, fmt.Errorf("loading adapter: %w", err)
 	}
+	return newGenerator(opts, g, adapter, entPkg, opts.SchemaPath)
+}
 
-	typesToGenerate, missing := matchTypes(g, protoTypes)
+// newGenerator matches the nodes of g with the proto messages and returns the
+// generator of their converters. Qualified names of field converters are
+// resolved from the directory dir.
+func newGenerator(opts *Options, g *gen.Graph, adapter *entproto.Adapter, entPkg, dir string) (*generator.Generator, error) {
+	protoTypes, err := loadProtoMessages(protoPaths(opts))
+	if err != nil {
+		return nil, fmt.Errorf("loading proto messages: %w", err)
+	}
+
+	typesToGenerate, missing, err := matchTypes(g, adapter, protoTypes, opts)
+	if err != nil {
+		return nil, err
+	}
 	if missing != nil {
 		switch normalizePolicy(opts.MissingProtoPolicy) {
 		case MissingProtoPolicyWarn:
@@ -227,20 +496,94 @@ func prepareGenerator(opts *Options) (*generator.Generator, error) {
 		return nil, fmt.Errorf("no matching types found between ent schema and proto messages")
 	}
 
-	adapter, err := loadAdapter(g)
+	fieldConverters, err := resolveFieldConverters(g, opts.FieldConverters, dir)
 	if err != nil {
-		return nil, fmt.Errorf("loading adapter: %w", err)
+		return nil, err
+	}
+
+	fieldTimeFormats, err := resolveFieldTimeFormats(g, opts.FieldTimeFormats)
+	if err != nil {
+		return nil, err
+	}
+
+	allowSensitive, err := resolveAllowSensitive(g, opts.AllowSensitive)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkCoveragePolicies(g, opts.TypeCoveragePolicies); err != nil {
+		return nil, err
 	}
 
-	return generator.New(
+	maxEdgeDepth := resolveMaxEdgeDepth(opts)
+	if err := validateProtoMessages(adapter, typesToGenerate, protoTypes, maxEdgeDepth > 0); err != nil {
+		return nil, fmt.Errorf("validating proto messages: %w", err)
+	}
+
+	cg := generator.New(
 		entPkg,
 		opts.ConvPackage,
-		opts.ProtoPackagePath,
-		resolveProtoAlias(opts),
 		typesToGenerate,
 		adapter,
 		g,
-	), nil
+	)
+	cg.MaxEdgeDepth = maxEdgeDepth
+	cg.Strict = opts.Strict
+	cg.FieldConverters = fieldConverters
+	cg.TimeFormat = converter.TimeFormat(opts.TimeFormat)
+	cg.FieldTimeFormats = fieldTimeFormats
+	cg.UnixZeroTime = opts.UnixZeroTime
+	cg.CloneMessages = opts.CloneMessages
+	cg.Tests = opts.Tests
+	cg.AllowSensitive = allowSensitive
+	cg.Templates = generatorTemplates(opts.Templates)
+	cg.TemplateFuncs = opts.TemplateFuncs
+	cg.Parallelism = opts.Parallelism
+	if err := checkTimeFormat(cg); err != nil {
+		return nil, err
+	}
+
+	report, err := fieldCoverage(cg)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyCoveragePolicies(opts, report); err != nil {
+		return nil, err
+	}
+	return cg, nil
+}
+
+// resolveAllowSensitive checks the allowed Sensitive fields against the graph
+// and returns them keyed by generator.FieldKey.
+func resolveAllowSensitive(g *gen.Graph, allow map[string][]string) (map[string]bool, error) {
+	out := make(map[string]bool)
+	for typeName, fields := range allow {
+		for _, f := range fields {
+			if err := checkEntField(g, typeName, f); err != nil {
+				return nil, fmt.Errorf("allowing sensitive field: %w", err)
+			}
+			out[generator.FieldKey(typeName, f)] = true
+		}
+	}
+	return out, nil
+}
+
+func checkEntField(g *gen.Graph, typeName, fieldName string) error {
+	for _, node := range g.Nodes {
+		if node.Name != typeName {
+			continue
+		}
+		if node.ID != nil && node.ID.Name == fieldName {
+			return nil
+		}
+		for _, f := range node.Fields {
+			if f.Name == fieldName {
+				return nil
+			}
+		}
+		return fmt.Errorf("ent type %s has no field %s", typeName, fieldName)
+	}
+	return fmt.Errorf("ent type %s not found", typeName)
 }
 
 func currentModulePath() string {
@@ -261,6 +604,17 @@ func resolveProtoAlias(opts *Options) string {
 	return opts.ConvPackage
 }
 
+func resolveMaxEdgeDepth(opts *Options) int {
+	switch {
+	case opts.MaxEdgeDepth == 0:
+		return DefaultMaxEdgeDepth
+	case opts.MaxEdgeDepth < 0:
+		return 0
+	default:
+		return opts.MaxEdgeDepth
+	}
+}
+
 func loadEntGraph(schemaPath, entPackage string, idType *field.TypeInfo) (*gen.Graph, error) {
 	return entc.LoadGraph(schemaPath, &gen.Config{
 		Package: entPackage,
@@ -272,117 +626,132 @@ func loadAdapter(g *gen.Graph) (*entproto.Adapter, error) {
 	return entproto.LoadAdapter(g)
 }
 
-func matchTypes(g *gen.Graph, protoTypes map[string]*generator.ProtoMessage) ([]generator.TypeInfo, *MissingProtoMessagesError) {
+func matchTypes(g *gen.Graph, adapter *entproto.Adapter, protoTypes map[string][]*generator.ProtoMessage, opts *Options) ([]generator.TypeInfo, *MissingProtoMessagesError, error) {
 	var typesToGenerate []generator.TypeInfo
 	missing := make([]string, 0)
 
 	for _, node := range g.Nodes {
-		protoType, ok := protoTypes[node.Name]
-		if !ok {
-			missing = append(missing, node.Name)
-			continue
+		var protoPkg, goPackageOption string
+		if fd, err := adapter.GetFileDescriptor(node.Name); err == nil {
+			protoPkg = fd.GetPackage()
+			goPackageOption, _, _ = strings.Cut(fd.GetFileOptions().GetGoPackage(), ";")
 		}
+		configured := protoPackageConfig(opts, protoPkg).GoPackage
+		for i, name := range messageNames(node, adapter, opts) {
+			candidates, ok := protoTypes[name]
+			if !ok && i > 0 {
+				return nil, nil, fmt.Errorf("proto message %s of ent type %s not found", name, node.Name)
+			}
+			if !ok {
+				missing = append(missing, node.Name)
+				break
+			}
+			protoType, goPkg, err := resolveProtoMessage(candidates, configured, goPackageOption, opts.ProtoPackagePath)
+			if err != nil {
+				return nil, nil, err
+			}
 
-		typesToGenerate = append(typesToGenerate, generator.TypeInfo{
-			MessageName: node.Name,
-			Message:     protoType,
-			Type:        node,
-		})
+			typesToGenerate = append(typesToGenerate, generator.TypeInfo{
+				MessageName:  name,
+				Message:      protoType,
+				Type:         node,
+				ProtoPackage: protoPkg,
+				GoPackage:    goPkg,
+				Subset:       i > 0,
+			})
+		}
 	}
+	assignProtoAliases(g, typesToGenerate, opts)
 	if len(missing) > 0 {
 		slices.Sort(missing)
-		return typesToGenerate, &MissingProtoMessagesError{Missing: missing}
+		return typesToGenerate, &MissingProtoMessagesError{Missing: missing}, nil
 	}
-	return typesToGenerate, nil
+	return typesToGenerate, nil, nil
 }
 
-func parseProtoFile(filePath string) (map[string]*generator.ProtoMessage, error) {
-	fset := token.NewFileSet()
-	node, err := parser.ParseFile(fset, filePath, nil, 0)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse file: %w", err)
+// messageNames returns the names of the proto messages of the ent type, the
+// message of the type first.
+func messageNames(node *gen.Type, adapter *entproto.Adapter, opts *Options) []string {
+	if names := opts.MessageNames[node.Name]; len(names) > 0 {
+		return names
 	}
+	if md, err := adapter.GetMessageDescriptor(node.Name); err == nil && md.GetName() != node.Name {
+		return []string{md.GetName()}
+	}
+	return []string{opts.MessagePrefix + node.Name + opts.MessageSuffix}
+}
 
-	result := make(map[string]*generator.ProtoMessage)
-
-	ast.Inspect(node, func(n ast.Node) bool {
-		typeSpec, ok := n.(*ast.TypeSpec)
-		if !ok {
-			return true
-		}
-
-		structType, ok := typeSpec.Type.(*ast.StructType)
-		if !ok {
-			return true
-		}
-
-		msg := &generator.ProtoMessage{
-			Name:   typeSpec.Name.Name,
-			Fields: parseStructFields(structType),
+// protoPackageConfig returns the configuration of the proto package name, or
+// the zero ProtoPackage.
+func protoPackageConfig(opts *Options, name string) ProtoPackage {
+	for _, p := range opts.ProtoPackages {
+		if p.Name == name {
+			return p
 		}
-		result[msg.Name] = msg
-		return true
-	})
-
-	return result, nil
+	}
+	return ProtoPackage{}
 }
 
-func parseStructFields(st *ast.StructType) []generator.ProtoField {
-	var fields []generator.ProtoField
-	for _, field := range st.Fields.List {
-		if len(field.Names) == 0 {
-			continue
+// assignProtoAliases sets the import alias of the proto Go package of each
+// type. Derived aliases are made unique among the imports of the converters.
+func assignProtoAliases(g *gen.Graph, types []generator.TypeInfo, opts *Options) {
+	aliases := make(map[string]string)
+	used := map[string]bool{"ent": true}
+	for _, node := range g.Nodes {
+		used[strings.ToLower(node.Name)] = true
+	}
+	if alias := resolveProtoAlias(opts); alias != "" {
+		aliases[opts.ProtoPackagePath] = alias
+		used[alias] = true
+	}
+	for i := range types {
+		t := &types[i]
+		alias, ok := aliases[t.GoPackage]
+		if !ok {
+			alias = protoPackageConfig(opts, t.ProtoPackage).Alias
+			if alias == "" {
+				alias = protoPackageAlias(t.ProtoPackage)
+				for base, n := alias, 2; used[alias]; n++ {
+					alias = fmt.Sprintf("%s%d", base, n)
+				}
+			}
+			aliases[t.GoPackage] = alias
+			used[alias] = true
 		}
-		fieldName := field.Names[0].Name
-
-		fields = append(fields, generator.ProtoField{
-			Name: fieldName,
-			Type: getTypeName(field.Type),
-		})
+		t.ProtoAlias = alias
 	}
-	return fields
 }
 
-func getTypeName(expr ast.Expr) string {
-	switch t := expr.(type) {
-	case *ast.Ident:
-		return t.Name
-	case *ast.StarExpr:
-		if name := getTypeName(t.X); name != "" {
-			return "*" + name
-		}
-	case *ast.SelectorExpr:
-		if pkg, ok := t.X.(*ast.Ident); ok {
-			return pkg.Name + "." + t.Sel.Name
-		}
-		return t.Sel.Name
-	case *ast.ArrayType:
-		if name := getTypeName(t.Elt); name != "" {
-			return "[]" + name
-		}
+// protoPackageAlias derives an import alias from a proto package name: its last
+// element, prefixed by the previous one for versions, e.g. userv1 for acme.user.v1.
+func protoPackageAlias(name string) string {
+	parts := strings.Split(name, ".")
+	alias := parts[len(parts)-1]
+	if len(parts) > 1 && len(alias) > 1 && alias[0] == 'v' && strings.Trim(alias[1:], "0123456789") == "" {
+		alias = parts[len(parts)-2] + alias
 	}
-	return ""
+	return strings.ToLower(alias)
 }
 
-func parseIDType(idType string) *field.TypeInfo {
+func parseIDType(idType string) (*field.TypeInfo, error) {
 	switch idType {
 	case "int":
-		return &field.TypeInfo{Type: field.TypeInt}
+		return &field.TypeInfo{Type: field.TypeInt}, nil
 	case "", "int64":
-		return &field.TypeInfo{Type: field.TypeInt64}
+		return &field.TypeInfo{Type: field.TypeInt64}, nil
 	case "uint":
-		return &field.TypeInfo{Type: field.TypeUint}
+		return &field.TypeInfo{Type: field.TypeUint}, nil
 	case "uint64":
-		return &field.TypeInfo{Type: field.TypeUint64}
+		return &field.TypeInfo{Type: field.TypeUint64}, nil
 	case "string":
-		return &field.TypeInfo{Type: field.TypeString}
+		return &field.TypeInfo{Type: field.TypeString}, nil
 	default:
-		return &field.TypeInfo{Type: field.TypeInt64}
+		return nil, fmt.Errorf("invalid IDType %q, declare other ID types, e.g. UUIDs, with an id field in the schema", idType)
 	}
 }
 
 func validateOptions(opts *Options) error {
-	if opts.ProtoFile == "" {
+	if opts.ProtoFile == "" && len(opts.ProtoPaths) == 0 {
 		return &RequiredOptionError{Field: "ProtoFile"}
 	}
 	if opts.SchemaPath == "" {
@@ -400,9 +769,23 @@ func validateOptions(opts *Options) error {
 	if opts.OutDir == "" {
 		return &RequiredOptionError{Field: "OutDir"}
 	}
+	if _, err := parseIDType(opts.IDType); err != nil {
+		return err
+	}
+	if opts.TimeFormat != "" && !converter.TimeFormat(opts.TimeFormat).Valid() {
+		return fmt.Errorf("invalid TimeFormat %q", opts.TimeFormat)
+	}
 	if p := normalizePolicy(opts.MissingProtoPolicy); p != MissingProtoPolicyStrict && p != MissingProtoPolicyWarn {
 		return fmt.Errorf("invalid MissingProtoPolicy %q", opts.MissingProtoPolicy)
 	}
+	if !validCoveragePolicy(opts.CoveragePolicy) {
+		return fmt.Errorf("invalid CoveragePolicy %q", opts.CoveragePolicy)
+	}
+	for typeName, p := range opts.TypeCoveragePolicies {
+		if !validCoveragePolicy(p) {
+			return fmt.Errorf("invalid CoveragePolicy %q of %s", p, typeName)
+		}
+	}
 	return nil
 }
 
@@ -0,0 +1,148 @@
+package entconv
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"entgo.io/ent"
+	"entgo.io/ent/entc/gen"
+	"entgo.io/ent/entc/load"
+	"entgo.io/ent/schema"
+	"entgo.io/ent/schema/edge"
+	"entgo.io/ent/schema/field"
+	"github.com/go-sphere/entc-extensions/entproto"
+)
+
+// benchEntities and benchFields size the synthetic schema of
+// BenchmarkRenderAll, modeled on a large project.
+const (
+	benchEntities = 150
+	benchFields   = 12
+)
+
+// BenchmarkRenderAll renders the converters of a synthetic schema of
+// benchEntities types, each with benchFields fields and an edge to the next
+// type, sequentially and with the default parallelism.
+func BenchmarkRenderAll(b *testing.B) {
+	g, protoDir := benchGraph(b)
+	adapter, err := loadAdapter(g)
+	if err != nil {
+		b.Fatalf("loadAdapter failed: %v", err)
+	}
+	opts := &Options{
+		EntPackagePath:   testEntPackagePath,
+		ProtoFile:        protoDir,
+		ConvPackage:      "entmap",
+		ProtoPackagePath: "example.com/bench/pb",
+		ProtoAlias:       "pb",
+		OutDir:           b.TempDir(),
+	}
+	cg, err := newGenerator(opts, g, adapter, testEntPackagePath, moduleRoot(b))
+	if err != nil {
+		b.Fatalf("newGenerator failed: %v", err)
+	}
+	for _, bc := range []struct {
+		name        string
+		parallelism int
+	}{
+		{name: "sequential", parallelism: 1},
+		{name: "parallel", parallelism: 0},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			for b.Loop() {
+				// A generator derived from cg does not share its parsed
+				// templates and field maps with the previous iterations.
+				run := cg.ForTypes(cg.ConvPackage, cg.Types)
+				run.Parallelism = bc.parallelism
+				files, err := run.RenderAll(opts.OutDir)
+				if err != nil {
+					b.Fatalf("RenderAll failed: %v", err)
+				}
+				if len(files) != benchEntities+1 {
+					b.Fatalf("rendered %d files, want %d", len(files), benchEntities+1)
+				}
+			}
+		})
+	}
+}
+
+// benchSchema is an ent schema with benchFields fields and an edge, named by
+// benchGraph.
+type benchSchema struct {
+	ent.Schema
+}
+
+func (benchSchema) Annotations() []schema.Annotation {
+	return []schema.Annotation{
+		entproto.Message(),
+	}
+}
+
+func (benchSchema) Fields() []ent.Field {
+	fields := make([]ent.Field, 0, benchFields)
+	for i := range benchFields {
+		name := fmt.Sprintf("field%d", i)
+		switch i % 3 {
+		case 0:
+			fields = append(fields, field.String(name).Annotations(entproto.Field(i+2)))
+		case 1:
+			fields = append(fields, field.Int64(name).Annotations(entproto.Field(i+2)))
+		default:
+			fields = append(fields, field.Bool(name).Annotations(entproto.Field(i+2)))
+		}
+	}
+	return fields
+}
+
+func (benchSchema) Edges() []ent.Edge {
+	return []ent.Edge{
+		edge.To("next", benchSchema.Type).
+			Unique().
+			Annotations(entproto.Field(benchFields + 2)),
+	}
+}
+
+// benchGraph returns a graph of benchEntities types named Entity<i>, whose
+// next edge points to the following type, and the directory of their pb
+// messages.
+func benchGraph(b *testing.B) (*gen.Graph, string) {
+	b.Helper()
+	buf, err := load.MarshalSchema(benchSchema{})
+	if err != nil {
+		b.Fatalf("MarshalSchema failed: %v", err)
+	}
+	schemas := make([]*load.Schema, 0, benchEntities)
+	var pb strings.Builder
+	pb.WriteString("package pb\n")
+	for i := range benchEntities {
+		s, err := load.UnmarshalSchema(buf)
+		if err != nil {
+			b.Fatalf("UnmarshalSchema failed: %v", err)
+		}
+		next := fmt.Sprintf("Entity%d", (i+1)%benchEntities)
+		s.Name = fmt.Sprintf("Entity%d", i)
+		s.Edges[0].Type = next
+		schemas = append(schemas, s)
+
+		fmt.Fprintf(&pb, "type %s struct {\n\tId int64\n", s.Name)
+		for j := range benchFields {
+			fmt.Fprintf(&pb, "\tField%d %s\n", j, [...]string{"string", "int64", "bool"}[j%3])
+		}
+		fmt.Fprintf(&pb, "\tNext *%s\n}\n", next)
+	}
+	g, err := gen.NewGraph(&gen.Config{
+		Package: testEntPackagePath,
+		IDType:  &field.TypeInfo{Type: field.TypeInt64},
+	}, schemas...)
+	if err != nil {
+		b.Fatalf("NewGraph failed: %v", err)
+	}
+	dir := b.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "bench.pb.go"), []byte(pb.String()), 0o644); err != nil {
+		b.Fatalf("write bench.pb.go: %v", err)
+	}
+	return g, dir
+}
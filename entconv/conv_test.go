@@ -3,13 +3,23 @@ package entconv
 import (
 	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"path"
 	"path/filepath"
 	"runtime"
 	"runtime/debug"
+	"slices"
 	"strings"
 	"testing"
+	"testing/fstest"
+	"text/template"
+	"time"
+
+	"entgo.io/ent/entc"
+	"entgo.io/ent/entc/gen"
+	"entgo.io/ent/schema/field"
+	"github.com/go-sphere/entc-extensions/entproto"
 )
 
 const (
@@ -106,6 +116,120 @@ func TestGenerateConverterFileWithOptions_GeneratesFile(t *testing.T) {
 	}
 }
 
+func TestGenerateConverterFile_RemovesStaleFilesAndKeepsUnchanged(t *testing.T) {
+	outDir := t.TempDir()
+	opts := testOptions(t, "pb")
+	opts.OutDir = outDir
+	if err := GenerateConverterFile(opts); err != nil {
+		t.Fatalf("GenerateConverterFile failed: %v", err)
+	}
+
+	user := filepath.Join(outDir, "user.go")
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(user, past, past); err != nil {
+		t.Fatal(err)
+	}
+	stale := filepath.Join(outDir, "comment.go")
+	own := filepath.Join(outDir, "helper.go")
+	if err := os.WriteFile(stale, []byte("// Code generated by protoc-gen-entconv. DO NOT EDIT.\npackage entmap\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(own, []byte("package entmap\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := GenerateConverterFile(opts); err != nil {
+		t.Fatalf("GenerateConverterFile failed: %v", err)
+	}
+
+	if info, err := os.Stat(user); err != nil || !info.ModTime().Equal(past) {
+		t.Fatalf("unchanged user.go should not be rewritten: %v", err)
+	}
+	if _, err := os.Stat(stale); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("stale generated file should be removed: %v", err)
+	}
+	if _, err := os.Stat(own); err != nil {
+		t.Fatalf("files without the generated header should be kept: %v", err)
+	}
+}
+
+func TestGenerateConverterFile_ParallelismDoesNotChangeOutput(t *testing.T) {
+	var outputs []map[string]string
+	for _, parallelism := range []int{1, 4} {
+		opts := testOptions(t, "pb")
+		opts.OutDir = t.TempDir()
+		opts.Parallelism = parallelism
+		if err := GenerateConverterFile(opts); err != nil {
+			t.Fatalf("GenerateConverterFile with parallelism %d failed: %v", parallelism, err)
+		}
+		entries, err := os.ReadDir(opts.OutDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files := make(map[string]string, len(entries))
+		for _, entry := range entries {
+			files[entry.Name()] = readFile(t, filepath.Join(opts.OutDir, entry.Name()))
+		}
+		outputs = append(outputs, files)
+	}
+	if !maps.Equal(outputs[0], outputs[1]) {
+		t.Fatal("the generated files should not depend on the parallelism")
+	}
+}
+
+func TestGenerateConverterFile_Check(t *testing.T) {
+	outDir := t.TempDir()
+	opts := testOptions(t, "pb")
+	opts.OutDir = outDir
+	opts.Check = true
+
+	var outOfDate *OutOfDateError
+	if err := GenerateConverterFile(opts); !errors.As(err, &outOfDate) {
+		t.Fatalf("expected *OutOfDateError for missing files, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "user.go")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatal("check mode should not write files")
+	}
+
+	opts.Check = false
+	if err := GenerateConverterFile(opts); err != nil {
+		t.Fatalf("GenerateConverterFile failed: %v", err)
+	}
+	opts.Check = true
+	if err := GenerateConverterFile(opts); err != nil {
+		t.Fatalf("up-to-date converters should pass the check: %v", err)
+	}
+
+	user := filepath.Join(outDir, "user.go")
+	edited := strings.Replace(readFile(t, user), "func ToProtoUser(", "func ToProtoUserEdited(", 1)
+	if err := os.WriteFile(user, []byte(edited), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	stale := filepath.Join(outDir, "comment.go")
+	if err := os.WriteFile(stale, []byte("// Code generated by protoc-gen-entconv. DO NOT EDIT.\npackage entmap\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	err := GenerateConverterFile(opts)
+	if !errors.As(err, &outOfDate) {
+		t.Fatalf("expected *OutOfDateError, got %v", err)
+	}
+	if want := []string{user, stale}; !slices.Equal(outOfDate.Files, want) {
+		t.Fatalf("out of date files = %v, want %v", outOfDate.Files, want)
+	}
+	for _, want := range []string{
+		"--- " + user + "\n+++ " + user + "\n",
+		"-func ToProtoUserEdited(",
+		"+func ToProtoUser(",
+		"+++ /dev/null\n",
+	} {
+		if !strings.Contains(outOfDate.Diff, want) {
+			t.Fatalf("diff should contain %q; diff:\n%s", want, outOfDate.Diff)
+		}
+	}
+	if readFile(t, user) != edited {
+		t.Fatal("check mode should not rewrite files")
+	}
+}
+
 func TestDefaultOptions_UsesScaffoldDefaults(t *testing.T) {
 	opts := DefaultOptions()
 	if opts.IDType != "int64" {
@@ -191,6 +315,774 @@ func TestGenerateConverter_WarnMissingProtoPolicyContinues(t *testing.T) {
 	}
 }
 
+func TestGenerateConverter_OneOf(t *testing.T) {
+	opts := fixtureOptions(t, "oneof")
+
+	code, err := GenerateConverter(opts)
+	if err != nil {
+		t.Fatalf("GenerateConverter failed: %v", err)
+	}
+	assertContains(t, string(code),
+		`case e.CardToken != "":`,
+		"v.Method = &pb.Payment_CardToken{CardToken: e.CardToken}",
+		"v.Method = &pb.Payment_WalletId{WalletId: e.WalletID}",
+		"switch x := v.Method.(type) {",
+		"case *pb.Payment_WalletId:",
+		"e.WalletID = x.WalletId",
+	)
+	if strings.Contains(string(code), "v.CardToken") {
+		t.Fatalf("oneof member assigned as a regular field; output:\n%s", code)
+	}
+	if strings.Contains(string(code), "setFields") {
+		t.Fatalf("non-strict code should not check the oneof fields set; output:\n%s", code)
+	}
+
+	// In strict mode, setting more than one field of the oneof is an error.
+	opts.Strict = true
+	code, err = GenerateConverter(opts)
+	if err != nil {
+		t.Fatalf("GenerateConverter failed: %v", err)
+	}
+	assertContains(t, string(code),
+		`if set := setFields([]string{"card_token", "wallet_id"}, e.CardToken != "", e.WalletID != ""); len(set) > 1 {`,
+		`return nil, &ConversionError{Type: "Payment", Field: "method", Value: set, Reason: "sets more than one field of the oneof"}`,
+		"func setFields(names []string, set ...bool) []string {",
+	)
+}
+
+func TestGenerateConverter_Strict(t *testing.T) {
+	opts := testOptions(t, "pb")
+	code, err := GenerateConverter(opts)
+	if err != nil {
+		t.Fatalf("GenerateConverter failed: %v", err)
+	}
+	if !strings.Contains(string(code), "type ConversionError struct") {
+		t.Fatalf("generated code missing ConversionError; output:\n%s", code)
+	}
+	if strings.Contains(string(code), "convertInt") {
+		t.Fatalf("non-strict code should not check conversions; output:\n%s", code)
+	}
+
+	opts.Strict = true
+	opts.OutDir = t.TempDir()
+	if err := GenerateConverterFile(opts); err != nil {
+		t.Fatalf("GenerateConverterFile failed: %v", err)
+	}
+	user, err := os.ReadFile(filepath.Join(opts.OutDir, "user.go"))
+	if err != nil {
+		t.Fatalf("reading user.go failed: %v", err)
+	}
+	// The int64 pb id may overflow the int ent id.
+	assertContains(t, string(user), `id, err := convertInt[int]("User", "id", v.Id)`)
+	shared, err := os.ReadFile(filepath.Join(opts.OutDir, "entconv.go"))
+	if err != nil {
+		t.Fatalf("reading entconv.go failed: %v", err)
+	}
+	assertContains(t, string(shared), "type ConversionError struct", "func convertInt[", "func convertEnum[")
+}
+
+func TestGenerateConverter_ListAndMapConverters(t *testing.T) {
+	code, err := GenerateConverter(testOptions(t, "pb"))
+	if err != nil {
+		t.Fatalf("GenerateConverter failed: %v", err)
+	}
+	assertContains(t, string(code),
+		"func ToProtoUserList(list []*ent.User) ([]*pb.User, error)",
+		"func ToEntUserList(list []*pb.User) ([]*ent.User, error)",
+		"func ToProtoUserMap(list []*ent.User) (map[int]*pb.User, error)",
+		"func ToEntUserMap(list []*pb.User) (map[int]*ent.User, error)",
+		"func ConvertList[From, To any]",
+		"func ConvertMap[K comparable, From any, To comparable]",
+	)
+}
+
+func TestGenerateConverter_LoadsProtoPackageDirectory(t *testing.T) {
+	opts := testOptions(t, "splitpb")
+	opts.ProtoFile = writeProtoPackage(t, map[string]string{
+		"user.pb.go": "package splitpb\ntype User struct {\n\tId   int64\n\tName string\n}\n",
+		"post.pb.go": "package splitpb\ntype Post struct {\n\tId    int64\n\tTitle string\n}\n",
+	})
+
+	code, err := GenerateConverter(opts)
+	if err != nil {
+		t.Fatalf("GenerateConverter failed: %v", err)
+	}
+	assertContains(t, string(code), "func ToProtoUser(", "func ToProtoPost(")
+}
+
+func TestGenerateConverter_ProtoPaths(t *testing.T) {
+	dir := writeProtoPackage(t, map[string]string{
+		"user.pb.go": "package splitpb\ntype User struct {\n\tId   int64\n\tName string\n}\n",
+		"post.pb.go": "package splitpb\ntype Post struct {\n\tId    int64\n\tTitle string\n}\n",
+	})
+	opts := testOptions(t, "splitpb")
+	opts.ProtoFile = ""
+	opts.ProtoPaths = []string{filepath.Join(dir, "user.pb.go"), filepath.Join(dir, "post.pb.go")}
+
+	code, err := GenerateConverter(opts)
+	if err != nil {
+		t.Fatalf("GenerateConverter failed: %v", err)
+	}
+	if !strings.Contains(string(code), "func ToProtoPost(") {
+		t.Fatal("generated code does not contain ToProtoPost")
+	}
+}
+
+func TestGenerateConverter_ProtoFieldTypeMismatch(t *testing.T) {
+	opts := testOptions(t, "badpb")
+	opts.ProtoFile = writeProtoPackage(t, map[string]string{
+		"fixture.pb.go": "package badpb\ntype User struct {\n\tId   string\n\tName string\n}\ntype Post struct {\n\tId int64\n}\n",
+	})
+
+	_, err := GenerateConverter(opts)
+	var mismatch *ProtoFieldMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("expected ProtoFieldMismatchError, got %T (%v)", err, err)
+	}
+	if !strings.Contains(err.Error(), "proto message User: field Id has type string, want int64") {
+		t.Fatalf("error does not report the Id mismatch: %v", err)
+	}
+	if !strings.Contains(err.Error(), "proto message Post has no field Title of type string") {
+		t.Fatalf("error does not report the missing Title field: %v", err)
+	}
+}
+
+func TestGenerateConverter_MultipleProtoPackages(t *testing.T) {
+	fixtureRoot := filepath.Join(moduleRoot(t), "testdata", "fixtures", "multipkg")
+	opts := testOptions(t, "pb")
+	opts.SchemaPath = filepath.Join(fixtureRoot, "schema")
+	opts.ProtoFile = filepath.Join(fixtureRoot, "grouppb")
+	opts.ProtoPaths = []string{filepath.Join(fixtureRoot, "userpb")}
+
+	code, err := GenerateConverter(opts)
+	if err != nil {
+		t.Fatalf("GenerateConverter failed: %v", err)
+	}
+	multipkg := path.Join(path.Dir(testProtoPackagePath), "multipkg")
+	assertContains(t, string(code),
+		fmt.Sprintf("groupv1 %q", path.Join(multipkg, "grouppb")),
+		fmt.Sprintf("userv1 %q", path.Join(multipkg, "userpb")),
+		"func toProtoGroup(e *ent.Group, path []any) (*groupv1.Group, error) {",
+		"func ToEntUser(v *userv1.User) (*ent.User, error) {",
+		"edge, err := toProtoUser(e.Edges.Owner, path)",
+	)
+}
+
+func TestGenerateConverterFile_ProtoPackageOutDir(t *testing.T) {
+	fixtureRoot := filepath.Join(moduleRoot(t), "testdata", "fixtures", "multipkg")
+	outDir := t.TempDir()
+	opts := testOptions(t, "pb")
+	opts.SchemaPath = filepath.Join(fixtureRoot, "schema")
+	opts.ProtoFile = filepath.Join(fixtureRoot, "grouppb")
+	opts.ProtoPaths = []string{filepath.Join(fixtureRoot, "userpb")}
+	opts.OutDir = filepath.Join(outDir, "entmap")
+	opts.ProtoPackages = []ProtoPackage{{
+		Name:   "acme.user.v1",
+		Alias:  "users",
+		OutDir: filepath.Join(outDir, "userconv"),
+	}}
+
+	if err := GenerateConverterFile(opts); err != nil {
+		t.Fatalf("GenerateConverterFile failed: %v", err)
+	}
+	user := readFile(t, filepath.Join(outDir, "userconv", "user.go"))
+	assertContains(t, user, "package userconv", "func ToProtoUser(e *ent.User) (*users.User, error) {")
+	group := readFile(t, filepath.Join(outDir, "entmap", "group.go"))
+	if !strings.Contains(group, "package entmap") || strings.Contains(group, "toProtoUser(") {
+		t.Fatalf("group.go should be in package entmap without converting the User edges; output:\n%s", group)
+	}
+	for _, dir := range []string{"userconv", "entmap"} {
+		if _, err := os.Stat(filepath.Join(outDir, dir, "entconv.go")); err != nil {
+			t.Fatalf("shared file not written to %s: %v", dir, err)
+		}
+	}
+}
+
+func TestProtoPackageAlias(t *testing.T) {
+	for name, want := range map[string]string{
+		"entpb":        "entpb",
+		"acme.user.v1": "userv1",
+		"acme.user":    "user",
+		"acme.vendor":  "vendor",
+	} {
+		if got := protoPackageAlias(name); got != want {
+			t.Errorf("protoPackageAlias(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestGenerateConverter_MessageNameRule(t *testing.T) {
+	opts := testOptions(t, "dto")
+	opts.ProtoFile = writeProtoPackage(t, map[string]string{
+		"fixture.pb.go": "package dto\ntype UserDTO struct {\n\tId   int64\n\tName string\n}\ntype PostDTO struct {\n\tId    int64\n\tTitle string\n}\n",
+	})
+	opts.MessageSuffix = "DTO"
+
+	code, err := GenerateConverter(opts)
+	if err != nil {
+		t.Fatalf("GenerateConverter failed: %v", err)
+	}
+	assertContains(t, string(code),
+		"func ToProtoUser(e *ent.User) (*dto.UserDTO, error) {",
+		"func ToEntPostList(list []*dto.PostDTO) ([]*ent.Post, error) {",
+	)
+}
+
+func TestGenerateConverter_SubsetMessage(t *testing.T) {
+	opts := testOptions(t, "pb")
+	opts.ProtoFile = writeProtoPackage(t, map[string]string{
+		"fixture.pb.go": "package pb\ntype User struct {\n\tId   int64\n\tName string\n}\ntype UserSummary struct {\n\tName string\n}\ntype Post struct {\n\tId    int64\n\tTitle string\n}\n",
+	})
+	opts.MessageNames = map[string][]string{"User": {"User", "UserSummary"}}
+
+	code, err := GenerateConverter(opts)
+	if err != nil {
+		t.Fatalf("GenerateConverter failed: %v", err)
+	}
+	src := string(code)
+	start := strings.Index(src, "func toProtoUserSummary(")
+	if start < 0 {
+		t.Fatalf("generated code missing toProtoUserSummary; output:\n%s", src)
+	}
+	summary := src[start : start+strings.Index(src[start:], "\n}\n")]
+	if !strings.Contains(summary, "v.Name = name") || strings.Contains(summary, "v.Id") {
+		t.Fatalf("toProtoUserSummary should convert only Name:\n%s", summary)
+	}
+	assertContains(t, src,
+		"func ToProtoUser(e *ent.User) (*pb.User, error) {",
+		"func ToEntUserSummary(v *pb.UserSummary) (*ent.User, error) {",
+		"func ToProtoUserSummaryMap(list []*ent.User) (map[int]*pb.UserSummary, error) {",
+	)
+
+	opts.MessageNames = map[string][]string{"User": {"User", "UserDetails"}}
+	if _, err := GenerateConverter(opts); err == nil || !strings.Contains(err.Error(), "proto message UserDetails of ent type User not found") {
+		t.Fatalf("expected an error for the missing subset message, got %v", err)
+	}
+}
+
+func TestGenerateConverter_EntprotoMessageName(t *testing.T) {
+	opts := fixtureOptions(t, "msgname")
+
+	code, err := GenerateConverter(opts)
+	if err != nil {
+		t.Fatalf("GenerateConverter failed: %v", err)
+	}
+	assertContains(t, string(code),
+		"func ToProtoAccount(e *ent.Account) (*pb.AccountEntity, error) {",
+		"toProtoAccount_StatusMap = map[account.Status]pb.AccountEntity_Status{",
+	)
+}
+
+func TestGenerateConverter_FieldMask(t *testing.T) {
+	code, err := GenerateConverter(testOptions(t, "pb"))
+	if err != nil {
+		t.Fatalf("GenerateConverter failed: %v", err)
+	}
+	assertContains(t, string(code),
+		"func ToProtoUserMasked(e *ent.User, mask *fieldmaskpb.FieldMask) (*pb.User, error) {",
+		"func MergeToEntUser(dst *ent.User, v *pb.User, mask *fieldmaskpb.FieldMask) error {",
+		`case "id", "name":`,
+		"out.Name = v.Name",
+		"dst.Name = e.Name",
+		"type FieldMaskError struct",
+	)
+}
+
+func TestGenerateConverter_FieldConverter(t *testing.T) {
+	opts := testOptions(t, "pb")
+	WithFieldConverter("User", "name", strings.ToUpper, strings.ToLower)(opts)
+	WithFieldConverter("Post", "title", "strconv.Quote", "strconv.Unquote")(opts)
+
+	code, err := GenerateConverter(opts)
+	if err != nil {
+		t.Fatalf("GenerateConverter failed: %v", err)
+	}
+	assertContains(t, string(code),
+		"name := strings.ToUpper(e.Name)",
+		"e.Name = strings.ToLower(v.Name)",
+		"title := strconv.Quote(e.Title)",
+		"title, err := strconv.Unquote(v.Title)",
+	)
+}
+
+func TestGenerateConverter_FieldConverterErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		option  Option
+		wantErr string
+	}{
+		{
+			name:    "unknown field",
+			option:  WithFieldConverter("User", "birthday", strings.ToUpper, strings.ToLower),
+			wantErr: "ent type User has no field birthday",
+		},
+		{
+			name:    "unknown function",
+			option:  WithFieldConverter("User", "name", "strings.ToUpper", "strings.NoSuchFunc"),
+			wantErr: "package strings has no function NoSuchFunc",
+		},
+		{
+			name:    "mismatched types",
+			option:  WithFieldConverter("User", "name", strings.ToUpper, strings.Count),
+			wantErr: "toEnt Count must be a func(P) T or func(P) (T, error)",
+		},
+		{
+			name:    "closure",
+			option:  WithFieldConverter("User", "name", func(s string) string { return s }, strings.ToLower),
+			wantErr: "is not declared at package level",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := testOptions(t, "pb")
+			tt.option(opts)
+			_, err := GenerateConverter(opts)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("GenerateConverter error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGenerateConverter_Templates(t *testing.T) {
+	templates := fstest.MapFS{
+		"trace.tmpl": {Data: []byte(`
+{{ define "entconv/import/additional/trace" }}trace "github.com/acme/trace"{{ end }}
+{{ define "entconv/type/additional/trace" }}
+// Trace{{ .TypeInfo.ConvName }} traces the converters of {{ shout .TypeInfo.Type.Name }}.
+func Trace{{ .TypeInfo.ConvName }}() { trace.Start("{{ .Generator.ConvPackage }}") }
+{{ end }}
+{{ define "entconv/additional/version" }}
+const Version = "v1"
+{{ end }}
+`)},
+		"post.tmpl": {Data: []byte(`{{ define "entconv/type/Post" }}
+// Post converters are written by hand.
+{{ end }}`)},
+	}
+	opts := append(testOptionFuncs(t, "pb"),
+		WithTemplates(templates, "*.tmpl"),
+		WithTemplateFuncs(template.FuncMap{"shout": strings.ToUpper}),
+	)
+	code, err := GenerateConverterWithOptions(opts...)
+	if err != nil {
+		t.Fatalf("GenerateConverterWithOptions failed: %v", err)
+	}
+	assertContains(t, string(code),
+		`trace "github.com/acme/trace"`,
+		"// TraceUser traces the converters of USER.",
+		`func TraceUser() { trace.Start("entmap") }`,
+		"func TracePost()",
+		"// Post converters are written by hand.",
+		`const Version = "v1"`,
+		"func ToProtoUser(",
+	)
+	if strings.Contains(string(code), "func ToProtoPost(") {
+		t.Fatalf("entconv/type/Post should replace the converters of Post; output:\n%s", code)
+	}
+
+	bad := fstest.MapFS{"bad.tmpl": {Data: []byte(`{{ define "entconv/type/additional/bad" }}{{ unknownFunc }}{{ end }}`)}}
+	if _, err := GenerateConverterWithOptions(append(testOptionFuncs(t, "pb"), WithTemplates(bad, "*.tmpl"))...); err == nil || !strings.Contains(err.Error(), "unknownFunc") {
+		t.Fatalf("expected a template parse error, got %v", err)
+	}
+}
+
+func TestGenerateConverter_RedactsSensitiveFields(t *testing.T) {
+	opts := fixtureOptions(t, "sensitive")
+	WithAllowSensitive("Account", "api_token")(opts)
+	var warnings []error
+	opts.WarningHandler = func(err error) { warnings = append(warnings, err) }
+
+	code, err := GenerateConverter(opts)
+	if err != nil {
+		t.Fatalf("GenerateConverter failed: %v", err)
+	}
+	toProto := string(code[strings.Index(string(code), "func toProtoAccount("):strings.Index(string(code), "func ToEntAccount(")])
+	if strings.Contains(toProto, "v.PasswordHash") {
+		t.Fatalf("ToProtoAccount should redact password_hash; output:\n%s", toProto)
+	}
+	if !strings.Contains(toProto, "v.ApiToken = ") {
+		t.Fatalf("ToProtoAccount should convert the allowed api_token; output:\n%s", toProto)
+	}
+	assertContains(t, string(code), "func ToProtoAccountUnredacted(e *ent.Account) (*pb.Account, error) {")
+	// The redaction is reported once, as a coverage gap, under every policy.
+	want := []CoverageGap{{Kind: GapSensitive, Type: "Account", Message: "Account", Field: "password_hash"}}
+	for _, policy := range []CoveragePolicy{CoveragePolicyWarn, CoveragePolicyIgnore, CoveragePolicyStrict} {
+		warnings = nil
+		WithCoveragePolicy(policy)(opts)
+		if _, err := GenerateConverter(opts); err != nil {
+			t.Fatalf("GenerateConverter with policy %s failed: %v", policy, err)
+		}
+		var report *CoverageReport
+		if len(warnings) != 1 || !errors.As(warnings[0], &report) || !slices.Equal(report.Gaps, want) {
+			t.Fatalf("warnings with policy %s = %v, want a *CoverageReport of Account.password_hash", policy, warnings)
+		}
+	}
+
+	WithAllowSensitive("Account", "secret")(opts)
+	if _, err := GenerateConverter(opts); err == nil || !strings.Contains(err.Error(), "ent type Account has no field secret") {
+		t.Fatalf("GenerateConverter error = %v, want an unknown field error", err)
+	}
+}
+
+func TestGenerateConverter_UUIDFields(t *testing.T) {
+	opts := fixtureOptions(t, "uuid")
+
+	code, err := GenerateConverter(opts)
+	if err != nil {
+		t.Fatalf("GenerateConverter failed: %v", err)
+	}
+	assertContains(t, string(code),
+		`uuid "github.com/google/uuid"`,
+		"id := uuidToString(e.ID)",
+		"token := uuidToBytes(e.Token)",
+		"parent_id := uuidToString(*e.ParentID)",
+		`id, err := uuidFromString[uuid.UUID]("Device", "id", v.Id)`,
+		`token, err := uuidFromBytes[uuid.UUID]("Device", "token", v.Token)`,
+		`parent_id, err := uuidFromString[uuid.UUID]("Device", "parent_id", *v.ParentId)`,
+		"func ToProtoDeviceMap(list []*ent.Device) (map[uuid.UUID]*pb.Device, error) {",
+		"func uuidFromString[",
+	)
+
+	opts.IDType = "uuid"
+	if _, err := GenerateConverter(opts); err == nil || !strings.Contains(err.Error(), `invalid IDType "uuid"`) {
+		t.Fatalf("GenerateConverter error = %v, want an invalid IDType error", err)
+	}
+}
+
+func TestGenerateConverter_TimeFormats(t *testing.T) {
+	opts := fixtureOptions(t, "timefmt")
+	WithFieldTimeFormat("Event", "created_at", TimeUnixMilli)(opts)
+
+	code, err := GenerateConverter(opts)
+	if err != nil {
+		t.Fatalf("GenerateConverter failed: %v", err)
+	}
+	assertContains(t, string(code),
+		"created_at := timeToUnixMilli(e.CreatedAt)",
+		"updated_at := timeToUnix(e.UpdatedAt)",
+		"started_at := timeToRFC3339(e.StartedAt)",
+		"ended_at := timeToTimestamp(*e.EndedAt)",
+		"deleted_at := timeToUnix(*e.DeletedAt)",
+		"e.CreatedAt = timeFromUnixMilli(v.CreatedAt)",
+		`started_at, err := timeFromRFC3339("Event", "started_at", v.StartedAt)`,
+		"if v.EndedAt != nil {",
+		"ended_at := timeFromTimestamp(v.EndedAt)",
+		"func timeFromUnixMilli(msec int64) time.Time {",
+		"func timeToTimestamp(t time.Time) *timestamppb.Timestamp {",
+	)
+	if strings.Contains(string(code), "func timeToUnixNano(") {
+		t.Fatal("generated code should only declare the helpers of the time formats in use")
+	}
+	// The Unix time 0 decodes to the epoch unless UnixZeroTime is set.
+	if strings.Contains(string(code), "if msec == 0 {") {
+		t.Fatalf("timeFromUnixMilli should decode 0 to the Unix epoch; output:\n%s", code)
+	}
+	WithUnixZeroTime(true)(opts)
+	code, err = GenerateConverter(opts)
+	if err != nil {
+		t.Fatalf("GenerateConverter failed: %v", err)
+	}
+	assertContains(t, string(code), "or the zero time for 0.\nfunc timeFromUnixMilli(msec int64) time.Time {\n\tif msec == 0 {\n\t\treturn time.Time{}")
+	opts.UnixZeroTime = false
+
+	opts.TimeFormat = TimeUnixNano
+	want := `time format "unix_nano" does not match the pb field type of Event.ended_at (TYPE_MESSAGE), Event.started_at (TYPE_STRING)`
+	if _, err := GenerateConverter(opts); err == nil || !strings.Contains(err.Error(), want) {
+		t.Fatalf("GenerateConverter error = %v, want a time format mismatch listing the fields", err)
+	}
+
+	// entproto emits int64 time fields, so a global format of another pb type
+	// is rejected at once for all the time fields without their own format.
+	opts.TimeFormat = TimeTimestamp
+	want = `time format "timestamp" does not match the pb field type of Event.deleted_at (TYPE_INT64), Event.started_at (TYPE_STRING), Event.updated_at (TYPE_INT64)`
+	if _, err := GenerateConverter(opts); err == nil || !strings.Contains(err.Error(), want) {
+		t.Fatalf("GenerateConverter error = %v, want a time format mismatch listing the fields", err)
+	}
+	opts.TimeFormat = TimeRFC3339
+	WithFieldTimeFormat("Event", "updated_at", TimeUnix)(opts)
+	WithFieldTimeFormat("Event", "ended_at", TimeTimestamp)(opts)
+	WithFieldTimeFormat("Event", "deleted_at", TimeUnixMicro)(opts)
+	code, err = GenerateConverter(opts)
+	if err != nil {
+		t.Fatalf("GenerateConverter with a global time format failed: %v", err)
+	}
+	assertContains(t, string(code),
+		"started_at := timeToRFC3339(e.StartedAt)",
+		"deleted_at := timeToUnixMicro(*e.DeletedAt)",
+		"ended_at := timeToTimestamp(*e.EndedAt)",
+	)
+
+	opts.TimeFormat = "unix_seconds"
+	if _, err := GenerateConverter(opts); err == nil || !strings.Contains(err.Error(), `invalid TimeFormat "unix_seconds"`) {
+		t.Fatalf("GenerateConverter error = %v, want an invalid TimeFormat error", err)
+	}
+	opts.TimeFormat = ""
+	WithFieldTimeFormat("Event", "id", TimeUnix)(opts)
+	if _, err := GenerateConverter(opts); err == nil || !strings.Contains(err.Error(), "is not a time field") {
+		t.Fatalf("GenerateConverter error = %v, want a non-time field error", err)
+	}
+}
+
+func TestGenerateConverter_JSONEncodedFields(t *testing.T) {
+	opts := fixtureOptions(t, "jsonenc")
+
+	code, err := GenerateConverter(opts)
+	if err != nil {
+		t.Fatalf("GenerateConverter failed: %v", err)
+	}
+	assertContains(t, string(code),
+		`settings, err := jsonToBytes("Widget", "settings", e.Settings)`,
+		`labels, err := jsonToString("Widget", "labels", e.Labels)`,
+		`settings, err := jsonFromBytes[schema.Settings]("Widget", "settings", v.Settings)`,
+		`labels, err := jsonFromString[map[string]string]("Widget", "labels", v.Labels)`,
+		"e.Tags = v.Tags",
+		"func jsonFromBytes[T any](typ, field string, b []byte) (T, error) {",
+	)
+	// A JSON field typed as bytes without entproto.EncodeJSON is not encoded,
+	// but left out as unsupported.
+	if strings.Contains(string(code), "Raw") {
+		t.Fatalf("raw should not be converted without entproto.EncodeJSON; output:\n%s", code)
+	}
+	opts.CoveragePolicy = CoveragePolicyStrict
+	if _, err := GenerateConverter(opts); err == nil || !strings.Contains(err.Error(), "Widget.raw (unsupported") {
+		t.Fatalf("GenerateConverter error = %v, want raw reported as unsupported", err)
+	}
+}
+
+func TestGenerateConverter_CloneMessages(t *testing.T) {
+	opts := fixtureOptions(t, "msgclone")
+	WithCloneMessages(true)(opts)
+
+	code, err := GenerateConverter(opts)
+	if err != nil {
+		t.Fatalf("GenerateConverter failed: %v", err)
+	}
+	assertContains(t, string(code),
+		"timeout := cloneMessage(e.Timeout)",
+		"checkpoints := cloneMessages(e.Checkpoints)",
+		"e.Timeout = cloneMessage(v.Timeout)",
+		"e.Checkpoints = cloneMessages(v.Checkpoints)",
+		"func cloneMessage[M proto.Message](m M) M {",
+	)
+
+	WithCloneMessages(false)(opts)
+	code, err = GenerateConverter(opts)
+	if err != nil {
+		t.Fatalf("GenerateConverter failed: %v", err)
+	}
+	if !strings.Contains(string(code), "e.Timeout = v.Timeout") || strings.Contains(string(code), "cloneMessage") {
+		t.Fatalf("generated code should assign the messages without cloning them; output:\n%s", code)
+	}
+	if !DefaultOptions().CloneMessages {
+		t.Fatal("messages should be cloned by default")
+	}
+}
+
+func TestGenerateConverterFile_Tests(t *testing.T) {
+	outDir := t.TempDir()
+	opts := fixtureOptions(t, "timefmt")
+	opts.OutDir = outDir
+	WithFieldTimeFormat("Event", "created_at", TimeUnixMilli)(opts)
+	WithTests(true)(opts)
+
+	if err := GenerateConverterFile(opts); err != nil {
+		t.Fatalf("GenerateConverterFile failed: %v", err)
+	}
+	code, err := os.ReadFile(filepath.Join(outDir, "event_test.go"))
+	if err != nil {
+		t.Fatalf("test file of Event not generated: %v", err)
+	}
+	assertContains(t, string(code),
+		// The Unix time 0 decodes to the epoch, so the zero time does not round-trip.
+		"e.CreatedAt = randomTime(r, time.Millisecond, false)",
+		"e.UpdatedAt = randomTime(r, time.Second, false)",
+		"e.StartedAt = randomTime(r, time.Nanosecond, true)",
+		"ended_at := randomTime(r, time.Nanosecond, false)",
+		"if !equalPtr(want.EndedAt, got.EndedAt, equalTime) {",
+		"func TestEventRoundTrip(t *testing.T) {",
+		"func FuzzEvent(f *testing.F) {",
+		"v := &pb.Event{}",
+	)
+	shared, err := os.ReadFile(filepath.Join(outDir, "entconv_test.go"))
+	if err != nil {
+		t.Fatalf("shared test file not generated: %v", err)
+	}
+	if !strings.Contains(string(shared), "func randomTime(r *rand.Rand, precision time.Duration, allowZero bool) time.Time {") {
+		t.Fatalf("shared test file missing randomTime; output:\n%s", shared)
+	}
+
+	WithUnixZeroTime(true)(opts)
+	if err := GenerateConverterFile(opts); err != nil {
+		t.Fatalf("GenerateConverterFile failed: %v", err)
+	}
+	assertContains(t, readFile(t, filepath.Join(outDir, "event_test.go")), "e.CreatedAt = randomTime(r, time.Millisecond, true)")
+
+	// The test files are removed with the option.
+	WithTests(false)(opts)
+	if err := GenerateConverterFile(opts); err != nil {
+		t.Fatalf("GenerateConverterFile failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "event_test.go")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("test files should not be generated without the option: %v", err)
+	}
+}
+
+func TestGenerateConverter_ReportsFieldCoverage(t *testing.T) {
+	opts := fixtureOptions(t, "coverage")
+	var warnings []error
+	opts.WarningHandler = func(err error) { warnings = append(warnings, err) }
+
+	want := []CoverageGap{
+		{Kind: GapEntField, Type: "Profile", Message: "Profile", Field: "bio"},
+		{Kind: GapProtoField, Type: "Profile", Message: "Profile", Field: "Legacy"},
+		{Kind: GapSensitive, Type: "Profile", Message: "Profile", Field: "secret"},
+		{Kind: GapUnsupported, Type: "Profile", Message: "Profile", Field: "seen_at", Reason: `entproto: no mapping from time field "seen_at" to pb field type "TYPE_BOOL"`},
+	}
+	report, err := Coverage(opts)
+	if err != nil {
+		t.Fatalf("Coverage failed: %v", err)
+	}
+	if !slices.Equal(report.Gaps, want) {
+		t.Fatalf("Coverage gaps = %+v, want %+v", report.Gaps, want)
+	}
+
+	warnings = nil
+	code, err := GenerateConverter(opts)
+	if err != nil {
+		t.Fatalf("GenerateConverter failed: %v", err)
+	}
+	if strings.Contains(string(code), "SeenAt") {
+		t.Fatalf("generated code should leave out the unsupported seen_at; output:\n%s", code)
+	}
+	// Each gap is warned once, the sensitive secret included.
+	var warned *CoverageReport
+	if len(warnings) != 1 || !errors.As(warnings[0], &warned) || !slices.Equal(warned.Gaps, want) {
+		t.Fatalf("warnings = %v, want a single *CoverageReport of the gaps", warnings)
+	}
+
+	// Edges left out of the converters are gaps.
+	edgeGap := func(o Options) CoverageGap {
+		t.Helper()
+		report, err := Coverage(&o)
+		if err != nil {
+			t.Fatalf("Coverage failed: %v", err)
+		}
+		i := slices.IndexFunc(report.Gaps, func(g CoverageGap) bool { return g.Kind == GapEdge })
+		if i < 0 {
+			t.Fatalf("Coverage gaps = %+v, want an edge gap", report.Gaps)
+		}
+		return report.Gaps[i]
+	}
+	noEdges := *opts
+	noEdges.MaxEdgeDepth = -1
+	if got, want := edgeGap(noEdges), (CoverageGap{Kind: GapEdge, Type: "Profile", Message: "Profile", Field: "team", Reason: "edge conversion is disabled"}); got != want {
+		t.Fatalf("edge gap = %+v, want %+v", got, want)
+	}
+	// Without its message, Team has no converters.
+	noTeam := *opts
+	noTeam.MissingProtoPolicy = MissingProtoPolicyWarn
+	noTeam.MessageNames = map[string][]string{"Team": {"TeamView"}}
+	if got, want := edgeGap(noTeam), (CoverageGap{Kind: GapEdge, Type: "Profile", Message: "Profile", Field: "team", Reason: "no converters are generated for Team"}); got != want {
+		t.Fatalf("edge gap = %+v, want %+v", got, want)
+	}
+
+	// A field of the message missing from an outdated Go struct is not a gap,
+	// whatever the policy.
+	stale := *opts
+	stale.CoveragePolicy = CoveragePolicyIgnore
+	stale.ProtoFile = writeProtoPackage(t, map[string]string{
+		"fixture.pb.go": "package pb\ntype Profile struct {\n\tId     int64\n\tSecret string\n\tSeenAt bool\n\tTeam   *Team\n}\ntype Team struct {\n\tId   int64\n\tName string\n}\n",
+	})
+	var mismatch *ProtoFieldMismatchError
+	if _, err := GenerateConverter(&stale); !errors.As(err, &mismatch) || mismatch.Field != "Name" {
+		t.Fatalf("GenerateConverter error = %v, want a *ProtoFieldMismatchError for Profile.Name", err)
+	}
+
+	// The redacted secret is warned under every policy and never fails the
+	// generation.
+	sensitive := []CoverageGap{want[2]}
+	warnings = nil
+	WithTypeCoveragePolicy("Profile", CoveragePolicyStrict)(opts)
+	if _, err := GenerateConverter(opts); !errors.As(err, &warned) || !slices.Equal(warned.Gaps, slices.Delete(slices.Clone(want), 2, 3)) {
+		t.Fatalf("GenerateConverter error = %v, want a *CoverageReport of the gaps but secret", err)
+	}
+	if len(warnings) != 1 || !errors.As(warnings[0], &warned) || !slices.Equal(warned.Gaps, sensitive) {
+		t.Fatalf("warnings = %v, want a *CoverageReport of secret", warnings)
+	}
+
+	warnings = nil
+	opts.CoveragePolicy = CoveragePolicyStrict
+	WithTypeCoveragePolicy("Profile", CoveragePolicyIgnore)(opts)
+	if _, err := GenerateConverter(opts); err != nil {
+		t.Fatalf("GenerateConverter with ignored gaps failed: %v", err)
+	}
+	if len(warnings) != 1 || !errors.As(warnings[0], &warned) || !slices.Equal(warned.Gaps, sensitive) {
+		t.Fatalf("warnings = %v, want a *CoverageReport of secret only", warnings)
+	}
+
+	WithTypeCoveragePolicy("Account", CoveragePolicyWarn)(opts)
+	if _, err := GenerateConverter(opts); err == nil || !strings.Contains(err.Error(), "ent type Account not found") {
+		t.Fatalf("GenerateConverter error = %v, want an unknown type error", err)
+	}
+
+	opts.CoveragePolicy = "loose"
+	if _, err := GenerateConverter(opts); err == nil || !strings.Contains(err.Error(), `invalid CoveragePolicy "loose"`) {
+		t.Fatalf("GenerateConverter error = %v, want an invalid policy error", err)
+	}
+}
+
+func TestExtension_UsesGraphAndProtoExtension(t *testing.T) {
+	fixtureRoot := filepath.Join(moduleRoot(t), "testdata", "fixtures")
+	outDir := t.TempDir()
+	protoExt, err := entproto.NewExtension()
+	if err != nil {
+		t.Fatalf("entproto.NewExtension failed: %v", err)
+	}
+	ex, err := NewExtension(
+		WithProtoFile(filepath.Join(fixtureRoot, "pb")),
+		WithProtoPackagePath(testProtoPackagePath),
+		WithProtoAlias("pb"),
+		WithOutDir(outDir),
+		WithProtoExtension(protoExt),
+	)
+	if err != nil {
+		t.Fatalf("NewExtension failed: %v", err)
+	}
+	const entPackage = "github.com/acme/other/ent"
+	g, err := entc.LoadGraph(filepath.Join(fixtureRoot, "schema"), &gen.Config{
+		Target:  filepath.Join(fixtureRoot, "ent"),
+		IDType:  &field.TypeInfo{Type: field.TypeInt64},
+		Package: entPackage,
+	})
+	if err != nil {
+		t.Fatalf("LoadGraph failed: %v", err)
+	}
+	if err := ex.generate(g); err != nil {
+		t.Fatalf("generate failed: %v", err)
+	}
+	adapter, err := protoExt.Adapter(g)
+	if err != nil || adapter == nil {
+		t.Fatalf("the entproto adapter should be loaded for the graph: %v", err)
+	}
+	if want := `ent "` + entPackage + `"`; !strings.Contains(readFile(t, filepath.Join(outDir, "user.go")), want) {
+		t.Fatalf("generated code should import the ent package of the graph %q", want)
+	}
+
+	if _, err := NewExtension(WithOutDir("")); err == nil {
+		t.Fatal("NewExtension should validate the options")
+	}
+}
+
+func TestResolveMaxEdgeDepth(t *testing.T) {
+	tests := map[int]int{0: DefaultMaxEdgeDepth, -1: 0, 1: 1, 5: 5}
+	for in, want := range tests {
+		if got := resolveMaxEdgeDepth(&Options{MaxEdgeDepth: in}); got != want {
+			t.Fatalf("resolveMaxEdgeDepth(%d)=%d, want %d", in, got, want)
+		}
+	}
+}
+
 func testOptions(t *testing.T, alias string) *Options {
 	t.Helper()
 
@@ -207,6 +1099,30 @@ func testOptions(t *testing.T, alias string) *Options {
 	}
 }
 
+// fixtureOptions returns the test options of the fixture testdata/fixtures/name,
+// with its schema and pb package.
+func fixtureOptions(t *testing.T, name string) *Options {
+	t.Helper()
+
+	fixtureRoot := filepath.Join(moduleRoot(t), "testdata", "fixtures", name)
+	opts := testOptions(t, "pb")
+	opts.SchemaPath = filepath.Join(fixtureRoot, "schema")
+	opts.ProtoFile = filepath.Join(fixtureRoot, "pb")
+	opts.ProtoPackagePath = path.Join(path.Dir(testProtoPackagePath), name, "pb")
+	return opts
+}
+
+// assertContains fails the test unless the generated code contains each of wants.
+func assertContains(t *testing.T, code string, wants ...string) {
+	t.Helper()
+
+	for _, want := range wants {
+		if !strings.Contains(code, want) {
+			t.Fatalf("generated code missing %q; output:\n%s", want, code)
+		}
+	}
+}
+
 func testOptionFuncs(t *testing.T, alias string) []Option {
 	t.Helper()
 
@@ -223,7 +1139,7 @@ func testOptionFuncs(t *testing.T, alias string) []Option {
 	}
 }
 
-func moduleRoot(t *testing.T) string {
+func moduleRoot(t testing.TB) string {
 	t.Helper()
 
 	_, file, _, ok := runtime.Caller(0)
@@ -236,9 +1152,29 @@ func moduleRoot(t *testing.T) string {
 func writeProtoFixture(t *testing.T, pkg, msg string) string {
 	t.Helper()
 	file := filepath.Join(t.TempDir(), "fixture.pb.go")
-	content := fmt.Sprintf("package %s\ntype %s struct{}\n", pkg, msg)
+	content := fmt.Sprintf("package %s\ntype %s struct {\n\tId   int64\n\tName string\n}\n", pkg, msg)
 	if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
 		t.Fatalf("write fixture pb.go: %v", err)
 	}
 	return file
 }
+
+func writeProtoPackage(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	return dir
+}
+
+func readFile(t *testing.T, name string) string {
+	t.Helper()
+	content, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatalf("read %s: %v", name, err)
+	}
+	return string(content)
+}
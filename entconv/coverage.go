@@ -0,0 +1,273 @@
+package entconv
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"entgo.io/ent/entc/gen"
+	"github.com/go-sphere/entc-extensions/entconv/internal/generator"
+)
+
+// CoveragePolicy decides what the gaps of the field coverage of an ent type do
+// to the generation, set with WithCoveragePolicy and WithTypeCoveragePolicy.
+type CoveragePolicy string
+
+const (
+	// CoveragePolicyWarn passes the gaps to the WarningHandler as a
+	// *CoverageReport. It is the default.
+	CoveragePolicyWarn CoveragePolicy = "warn"
+	// CoveragePolicyStrict fails the generation with a *CoverageReport of the
+	// gaps other than GapSensitive.
+	CoveragePolicyStrict CoveragePolicy = "strict"
+	// CoveragePolicyIgnore drops the gaps other than GapSensitive.
+	CoveragePolicyIgnore CoveragePolicy = "ignore"
+)
+
+// GapKind is the kind of a CoverageGap.
+type GapKind string
+
+const (
+	// GapEntField is an ent field or edge without a field in the proto message,
+	// e.g. annotated with entproto.Skip. Subset messages are not checked for
+	// them. A field of the message missing from its Go struct, e.g. of an
+	// outdated .pb.go file, fails generation with a *ProtoFieldMismatchError
+	// instead.
+	GapEntField GapKind = "ent_field"
+	// GapEdge is an edge of the proto message left out of the converters,
+	// because edge conversion is disabled or its target type has no
+	// converters.
+	GapEdge GapKind = "edge"
+	// GapProtoField is an exported field of the proto message struct without an
+	// ent field or edge.
+	GapProtoField GapKind = "proto_field"
+	// GapSensitive is a Sensitive ent field redacted from ToProto, see
+	// WithAllowSensitive. It is passed to the WarningHandler under every
+	// CoveragePolicy: redacting is safe, so it does not fail the generation,
+	// and it is never ignored.
+	GapSensitive GapKind = "sensitive"
+	// GapUnsupported is a field whose ent and pb types have no conversion, left
+	// out of the converters.
+	GapUnsupported GapKind = "unsupported"
+)
+
+// CoverageGap is a field of an ent type or of its proto message that the
+// generated converters do not convert.
+type CoverageGap struct {
+	Kind GapKind
+	// Type is the ent type and Message the proto message.
+	Type    string
+	Message string
+	// Field is the name of the ent field or edge, or the Go name of the struct
+	// field for GapProtoField.
+	Field string
+	// Reason tells why the field is left out, if not by the proto message.
+	Reason string
+}
+
+func (g CoverageGap) String() string {
+	s := fmt.Sprintf("%s.%s (%s", g.Message, g.Field, g.Kind)
+	if g.Reason != "" {
+		s += ": " + g.Reason
+	}
+	return s + ")"
+}
+
+// CoverageReport lists the fields left out by the generated converters. It is
+// returned by Coverage, passed to the WarningHandler for the types under
+// CoveragePolicyWarn and for GapSensitive, and returned by the generation for
+// the types under CoveragePolicyStrict.
+type CoverageReport struct {
+	// Gaps are sorted by type, in the order of the graph, then by message,
+	// kind and field.
+	Gaps []CoverageGap
+}
+
+func (e *CoverageReport) Error() string {
+	gaps := make([]string, len(e.Gaps))
+	for i, g := range e.Gaps {
+		gaps[i] = g.String()
+	}
+	return fmt.Sprintf("converters leave out fields: %s", strings.Join(gaps, ", "))
+}
+
+// WithCoveragePolicy sets the CoveragePolicy of the types without a
+// WithTypeCoveragePolicy, e.g. to fail on any gap:
+//
+//	entconv.WithCoveragePolicy(entconv.CoveragePolicyStrict)
+func WithCoveragePolicy(p CoveragePolicy) Option {
+	return func(o *Options) {
+		o.CoveragePolicy = p
+	}
+}
+
+// WithTypeCoveragePolicy sets the CoveragePolicy of a single ent type.
+func WithTypeCoveragePolicy(typeName string, p CoveragePolicy) Option {
+	return func(o *Options) {
+		if o.TypeCoveragePolicies == nil {
+			o.TypeCoveragePolicies = make(map[string]CoveragePolicy)
+		}
+		o.TypeCoveragePolicies[typeName] = p
+	}
+}
+
+// Coverage returns the field coverage of the converters generated with opts,
+// regardless of their CoveragePolicy.
+func Coverage(opts *Options) (*CoverageReport, error) {
+	o := *opts
+	o.CoveragePolicy, o.TypeCoveragePolicies = CoveragePolicyIgnore, nil
+	cg, err := prepareGenerator(&o)
+	if err != nil {
+		return nil, err
+	}
+	return fieldCoverage(cg)
+}
+
+// fieldCoverage returns the fields left out by the converters of cg.
+func fieldCoverage(cg *generator.Generator) (*CoverageReport, error) {
+	report := &CoverageReport{}
+	for _, ti := range cg.Types {
+		gap := func(kind GapKind, field, reason string) {
+			report.Gaps = append(report.Gaps, CoverageGap{Kind: kind, Type: ti.Type.Name, Message: ti.MessageName, Field: field, Reason: reason})
+		}
+		fm, err := generator.MessageFieldMap(cg.Adapter, ti)
+		if err != nil {
+			return nil, err
+		}
+		if !ti.Subset {
+			for _, name := range entFieldNames(ti.Type) {
+				if _, ok := fm[name]; !ok {
+					gap(GapEntField, name, "")
+				}
+			}
+		}
+		mapped := make(map[string]bool, len(fm))
+		for _, f := range fm {
+			mapped[f.PbFieldName()] = true
+		}
+		for _, o := range fm.OneOfs() {
+			mapped[o.PbFieldName()] = true
+		}
+		for _, f := range ti.Message.Fields {
+			if !mapped[f.Name] && isExported(f.Name) {
+				gap(GapProtoField, f.Name, "")
+			}
+		}
+		for _, f := range generator.RedactedFields(fm, ti, cg.AllowSensitive) {
+			gap(GapSensitive, f.EntField.Name, "")
+		}
+		unsupported, err := cg.UnsupportedFields(ti)
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range unsupported {
+			gap(GapUnsupported, u.Field.EntField.Name, u.Err.Error())
+		}
+		edges, err := cg.UnconvertedEdges(ti)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range edges {
+			gap(GapEdge, e.Edge.EntEdge.Name, e.Reason)
+		}
+	}
+	order := make(map[string]int, len(cg.Types))
+	for i, ti := range cg.Types {
+		if _, ok := order[ti.Type.Name]; !ok {
+			order[ti.Type.Name] = i
+		}
+	}
+	slices.SortStableFunc(report.Gaps, func(a, b CoverageGap) int {
+		if c := order[a.Type] - order[b.Type]; c != 0 {
+			return c
+		}
+		if c := strings.Compare(a.Message, b.Message); c != 0 {
+			return c
+		}
+		if c := strings.Compare(string(a.Kind), string(b.Kind)); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Field, b.Field)
+	})
+	return report, nil
+}
+
+// entFieldNames returns the names of the ID, fields and edges of the ent type.
+func entFieldNames(t *gen.Type) []string {
+	var names []string
+	if t.ID != nil {
+		names = append(names, t.ID.Name)
+	}
+	for _, f := range t.Fields {
+		names = append(names, f.Name)
+	}
+	for _, e := range t.Edges {
+		names = append(names, e.Name)
+	}
+	return names
+}
+
+func isExported(name string) bool {
+	for _, r := range name {
+		return unicode.IsUpper(r)
+	}
+	return false
+}
+
+// applyCoveragePolicies passes the gaps of report under CoveragePolicyWarn and
+// the GapSensitive gaps to the WarningHandler, and returns the other gaps under
+// CoveragePolicyStrict.
+func applyCoveragePolicies(opts *Options, report *CoverageReport) error {
+	var warn, strict CoverageReport
+	for _, g := range report.Gaps {
+		switch policy := coveragePolicy(opts, g.Type); {
+		case g.Kind == GapSensitive, policy == CoveragePolicyWarn:
+			warn.Gaps = append(warn.Gaps, g)
+		case policy == CoveragePolicyStrict:
+			strict.Gaps = append(strict.Gaps, g)
+		}
+	}
+	if len(warn.Gaps) > 0 && opts.WarningHandler != nil {
+		opts.WarningHandler(&warn)
+	}
+	if len(strict.Gaps) > 0 {
+		return &strict
+	}
+	return nil
+}
+
+// coveragePolicy returns the CoveragePolicy of the ent type.
+func coveragePolicy(opts *Options, typeName string) CoveragePolicy {
+	if p, ok := opts.TypeCoveragePolicies[typeName]; ok {
+		return normalizeCoveragePolicy(p)
+	}
+	return normalizeCoveragePolicy(opts.CoveragePolicy)
+}
+
+func normalizeCoveragePolicy(v CoveragePolicy) CoveragePolicy {
+	if v == "" {
+		return CoveragePolicyWarn
+	}
+	return CoveragePolicy(strings.ToLower(string(v)))
+}
+
+// validCoveragePolicy reports whether v is a CoveragePolicy, or empty.
+func validCoveragePolicy(v CoveragePolicy) bool {
+	switch normalizeCoveragePolicy(v) {
+	case CoveragePolicyWarn, CoveragePolicyStrict, CoveragePolicyIgnore:
+		return true
+	}
+	return false
+}
+
+// checkCoveragePolicies checks the ent types of the coverage policies against
+// the graph.
+func checkCoveragePolicies(g *gen.Graph, policies map[string]CoveragePolicy) error {
+	for typeName := range policies {
+		if !slices.ContainsFunc(g.Nodes, func(n *gen.Type) bool { return n.Name == typeName }) {
+			return fmt.Errorf("coverage policy: ent type %s not found", typeName)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package entconv
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"entgo.io/ent/entc"
+	"entgo.io/ent/entc/gen"
+	"github.com/go-sphere/entc-extensions/entconv/internal/pkgutil"
+	"github.com/go-sphere/entc-extensions/entproto"
+)
+
+// Extension is an entc.Extension that generates the converters in the ent
+// codegen pass, after the ent code. It reuses the graph loaded by entc, takes
+// the ent package from gen.Config.Package and, with WithProtoExtension, shares
+// the adapter of the entproto extension:
+//
+//	protoExt, err := entproto.NewExtension(entproto.WithProtoDir("./proto"))
+//	...
+//	convExt, err := entconv.NewExtension(entconv.WithProtoExtension(protoExt))
+//	...
+//	err = entc.Generate("./schema", &gen.Config{}, entc.Extensions(protoExt, convExt))
+//
+// The proto messages are loaded from the .pb.go files on disk, so messages added
+// to the schema are converted once their .proto files have been compiled.
+type Extension struct {
+	entc.DefaultExtension
+	opts []Option
+}
+
+// NewExtension returns a new Extension configured by opts. The defaults of
+// NewOptions apply, with the import paths of the module of gen.Config.Target.
+// The ent package is always gen.Config.Package.
+func NewExtension(opts ...Option) (*Extension, error) {
+	if err := validateOptions(newOptions("", opts)); err != nil {
+		return nil, fmt.Errorf("entconv: %w", err)
+	}
+	return &Extension{opts: opts}, nil
+}
+
+// WithProtoExtension makes the extension convert with the adapter of the
+// entproto extension of the same codegen pass, instead of loading its own.
+func WithProtoExtension(ex *entproto.Extension) Option {
+	return func(o *Options) {
+		o.ProtoExtension = ex
+	}
+}
+
+// Hooks implements entc.Extension.
+func (e *Extension) Hooks() []gen.Hook {
+	return []gen.Hook{e.hook()}
+}
+
+func (e *Extension) hook() gen.Hook {
+	return func(next gen.Generator) gen.Generator {
+		return gen.GenerateFunc(func(g *gen.Graph) error {
+			if err := next.Generate(g); err != nil {
+				return err
+			}
+			return e.generate(g)
+		})
+	}
+}
+
+func (e *Extension) generate(g *gen.Graph) error {
+	target, err := filepath.Abs(g.Target)
+	if err != nil {
+		return fmt.Errorf("entconv: %w", err)
+	}
+	root, err := pkgutil.FindModuleRoot(target)
+	if err != nil {
+		return fmt.Errorf("entconv: finding the module of %s: %w", g.Target, err)
+	}
+	modulePath, err := pkgutil.GetModulePath(root)
+	if err != nil {
+		return fmt.Errorf("entconv: %w", err)
+	}
+	opts := newOptions(modulePath, e.opts)
+	opts.EntPackagePath = g.Package
+	if err := validateOptions(opts); err != nil {
+		return fmt.Errorf("entconv: %w", err)
+	}
+
+	var adapter *entproto.Adapter
+	if opts.ProtoExtension != nil {
+		adapter, err = opts.ProtoExtension.Adapter(g)
+	} else {
+		adapter, err = loadAdapter(g)
+	}
+	if err != nil {
+		return fmt.Errorf("entconv: loading adapter: %w", err)
+	}
+	cg, err := newGenerator(opts, g, adapter, g.Package, root)
+	if err != nil {
+		return fmt.Errorf("entconv: %w", err)
+	}
+	if err := writeConverterFiles(cg, opts); err != nil {
+		return fmt.Errorf("entconv: %w", err)
+	}
+	return nil
+}
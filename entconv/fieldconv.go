@@ -0,0 +1,169 @@
+package entconv
+
+import (
+	"fmt"
+	"go/types"
+	"reflect"
+	"strings"
+
+	"entgo.io/ent/entc/gen"
+	"github.com/go-sphere/entc-extensions/entconv/internal/generator"
+	"github.com/go-sphere/entc-extensions/entproto"
+	"golang.org/x/tools/go/packages"
+)
+
+// FieldConverter overrides the conversion of a single ent field, set with
+// WithFieldConverter.
+type FieldConverter struct {
+	// Type and Field name the ent type and field, e.g. "User" and "birthday".
+	Type  string
+	Field string
+	// ToProto converts the ent value to the pb value and ToEnt converts it
+	// back, optionally returning an error as its second result. Each is either
+	// a package-level function or its qualified name, e.g. "strconv.Itoa" or
+	// "github.com/acme/app/conv.MoneyToProto".
+	ToProto any
+	ToEnt   any
+}
+
+// WithFieldConverter makes the generated converters call toProto and toEnt to
+// convert the field of the ent type instead of the built-in conversion, e.g.
+// for JSON structs or custom Go types:
+//
+//	entconv.WithFieldConverter("User", "birthday", conv.DateToProto, conv.DateToEnt)
+//	entconv.WithFieldConverter("User", "birthday", "github.com/acme/app/conv.DateToProto", "github.com/acme/app/conv.DateToEnt")
+//
+// The functions are called with the non-nil value of Nillable and proto3
+// optional fields. Their packages are imported by the generated code.
+func WithFieldConverter(typeName, fieldName string, toProto, toEnt any) Option {
+	return func(o *Options) {
+		o.FieldConverters = append(o.FieldConverters, FieldConverter{Type: typeName, Field: fieldName, ToProto: toProto, ToEnt: toEnt})
+	}
+}
+
+// resolveFieldConverters checks the configured field converters against the
+// graph and resolves their functions, keyed by "Type.field". Qualified names
+// are type-checked from the directory dir.
+func resolveFieldConverters(g *gen.Graph, convs []FieldConverter, dir string) (map[string]generator.FieldFuncs, error) {
+	if len(convs) == 0 {
+		return nil, nil
+	}
+	out := make(map[string]generator.FieldFuncs, len(convs))
+	r := &funcResolver{dir: dir, pkgs: make(map[string]*types.Package)}
+	for _, c := range convs {
+		if err := checkEntField(g, c.Type, c.Field); err != nil {
+			return nil, fmt.Errorf("field converter: %w", err)
+		}
+		toProto, err := r.resolve(c.ToProto)
+		if err != nil {
+			return nil, fmt.Errorf("field converter of %s.%s: toProto: %w", c.Type, c.Field, err)
+		}
+		toEnt, err := r.resolve(c.ToEnt)
+		if err != nil {
+			return nil, fmt.Errorf("field converter of %s.%s: toEnt: %w", c.Type, c.Field, err)
+		}
+		if err := checkFieldFuncs(toProto, toEnt); err != nil {
+			return nil, fmt.Errorf("field converter of %s.%s: %w", c.Type, c.Field, err)
+		}
+		out[generator.FieldKey(c.Type, c.Field)] = generator.FieldFuncs{
+			ToProto:           toProto.GoFunc,
+			ToEnt:             toEnt.GoFunc,
+			ToEntReturnsError: toEnt.returnsError(),
+		}
+	}
+	return out, nil
+}
+
+// convFunc is a resolved conversion function with its parameter and result types.
+type convFunc struct {
+	entproto.GoFunc
+	params  []string
+	results []string
+}
+
+func (f convFunc) returnsError() bool {
+	return len(f.results) == 2
+}
+
+func checkFieldFuncs(toProto, toEnt convFunc) error {
+	switch {
+	case len(toProto.params) != 1 || len(toProto.results) != 1:
+		return fmt.Errorf("toProto %s must be a func(T) P", toProto.Name)
+	case len(toEnt.params) != 1 || len(toEnt.results) < 1 || len(toEnt.results) > 2 ||
+		toEnt.returnsError() && toEnt.results[1] != "error":
+		return fmt.Errorf("toEnt %s must be a func(P) T or func(P) (T, error)", toEnt.Name)
+	case toProto.params[0] != toEnt.results[0] || toProto.results[0] != toEnt.params[0]:
+		return fmt.Errorf("toProto %s and toEnt %s do not convert between the same types", toProto.Name, toEnt.Name)
+	}
+	return nil
+}
+
+// funcResolver resolves conversion functions, caching the packages loaded for
+// qualified names.
+type funcResolver struct {
+	dir  string
+	pkgs map[string]*types.Package
+}
+
+func (r *funcResolver) resolve(fn any) (convFunc, error) {
+	if name, ok := fn.(string); ok {
+		return r.resolveName(name)
+	}
+	f, err := entproto.GoFuncOf(fn)
+	if err != nil {
+		return convFunc{}, err
+	}
+	out := convFunc{GoFunc: f}
+	t := reflect.TypeOf(fn)
+	for i := range t.NumIn() {
+		out.params = append(out.params, t.In(i).String())
+	}
+	for i := range t.NumOut() {
+		out.results = append(out.results, t.Out(i).String())
+	}
+	return out, nil
+}
+
+// resolveName resolves a qualified function name, e.g. "strconv.Itoa", by
+// type-checking its package.
+func (r *funcResolver) resolveName(name string) (convFunc, error) {
+	slash := strings.LastIndex(name, "/")
+	dot := strings.Index(name[slash+1:], ".")
+	if dot < 0 {
+		return convFunc{}, fmt.Errorf("%q is not a qualified function name", name)
+	}
+	f := entproto.GoFunc{PkgPath: name[:slash+1+dot], Name: name[slash+1+dot+1:]}
+	pkg, ok := r.pkgs[f.PkgPath]
+	if !ok {
+		cfg := &packages.Config{Mode: packages.NeedName | packages.NeedTypes, Dir: r.dir}
+		pkgs, err := packages.Load(cfg, f.PkgPath)
+		if err != nil {
+			return convFunc{}, fmt.Errorf("loading package %s: %w", f.PkgPath, err)
+		}
+		if len(pkgs) != 1 {
+			return convFunc{}, fmt.Errorf("loading package %s: found %d packages", f.PkgPath, len(pkgs))
+		}
+		if len(pkgs[0].Errors) > 0 {
+			return convFunc{}, fmt.Errorf("loading package %s: %v", f.PkgPath, pkgs[0].Errors[0])
+		}
+		pkg = pkgs[0].Types
+		r.pkgs[f.PkgPath] = pkg
+	}
+	obj, ok := pkg.Scope().Lookup(f.Name).(*types.Func)
+	if !ok {
+		return convFunc{}, fmt.Errorf("package %s has no function %s", f.PkgPath, f.Name)
+	}
+	sig := obj.Type().(*types.Signature)
+	if sig.TypeParams().Len() > 0 {
+		return convFunc{}, fmt.Errorf("function %s is generic", name)
+	}
+	out := convFunc{GoFunc: f}
+	qualifier := func(p *types.Package) string { return p.Name() }
+	for v := range sig.Params().Variables() {
+		out.params = append(out.params, types.TypeString(v.Type(), qualifier))
+	}
+	for v := range sig.Results().Variables() {
+		out.results = append(out.results, types.TypeString(v.Type(), qualifier))
+	}
+	return out, nil
+}
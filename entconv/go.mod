@@ -1,10 +1,11 @@
 module github.com/go-sphere/entc-extensions/entconv
 
-go 1.25
+go 1.25.0
 
 require (
 	entgo.io/ent v0.14.5
 	github.com/go-sphere/entc-extensions/entproto v0.0.0-00010101000000-000000000000
+	github.com/google/uuid v1.6.0
 	github.com/jhump/protoreflect v1.10.1
 	golang.org/x/tools v0.42.0
 	google.golang.org/protobuf v1.36.11
@@ -19,15 +20,16 @@ require (
 	github.com/go-viper/mapstructure/v2 v2.5.0 // indirect
 	github.com/golang/protobuf v1.5.4 // indirect
 	github.com/google/go-cmp v0.7.0 // indirect
-	github.com/google/uuid v1.6.0 // indirect
 	github.com/hashicorp/hcl/v2 v2.18.1 // indirect
 	github.com/mitchellh/go-wordwrap v1.0.1 // indirect
 	github.com/zclconf/go-cty v1.14.4 // indirect
 	github.com/zclconf/go-cty-yaml v1.1.0 // indirect
 	go.uber.org/multierr v1.11.0 // indirect
 	golang.org/x/mod v0.33.0 // indirect
-	golang.org/x/sync v0.19.0 // indirect
-	golang.org/x/text v0.23.0 // indirect
+	golang.org/x/net v0.52.0 // indirect
+	golang.org/x/sync v0.20.0 // indirect
+	golang.org/x/text v0.35.0 // indirect
+	google.golang.org/genproto/googleapis/api v0.0.0-20260401024825-9d38bb4040a9 // indirect
 )
 
 replace github.com/go-sphere/entc-extensions/entproto => ../entproto
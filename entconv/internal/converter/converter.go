@@ -23,26 +23,110 @@ type BinaryMarshallerUnmarshaler interface {
 	encoding.BinaryUnmarshaler
 }
 
+// UnsupportedError reports a field whose ent and pb types have no conversion.
+type UnsupportedError struct {
+	Reason string
+}
+
+func (e *UnsupportedError) Error() string {
+	return e.Reason
+}
+
+func unsupported(format string, args ...any) error {
+	return &UnsupportedError{Reason: fmt.Sprintf(format, args...)}
+}
+
 // Converter holds conversion information for a single field.
 type Converter struct {
 	ToEntConversion              string
-	ToEntConversionArg           string // Additional argument for conversion (e.g., nanoseconds for time.Unix)
 	ToEntScannerConversion       string
 	ToEntConstructor             string
 	ToEntMarshallerConstructor   string
 	ToEntScannerConstructor      string
 	ToEntModifier                string
 	ToProtoConversion            string
-	ToProtoConversionModifier    string // Postfix to apply (e.g., .Unix() for time fields)
 	ToProtoConstructor           string
 	ToProtoMarshallerConstructor string
 	ToProtoValuer                string
+	// ToProtoFunc and ToEntFunc are the user functions converting field.Other
+	// values, registered with entproto.RegisterOtherType, or a single field
+	// configured with entconv.WithFieldConverter.
+	ToProtoFunc           entproto.GoFunc
+	ToEntFunc             entproto.GoFunc
+	ToEntFuncReturnsError bool
+	// ToProtoNarrows and ToEntNarrows report whether ToProtoConversion and
+	// ToEntConversion are integer conversions that may overflow.
+	ToProtoNarrows bool
+	ToEntNarrows   bool
+	// ToProtoEnumMap and ToEntEnumMap name the generated maps between the ent
+	// and pb values of an enum field.
+	ToProtoEnumMap string
+	ToEntEnumMap   string
+	// ToEntUUID names the generated function parsing the pb string or bytes
+	// value of a UUID field into UUIDType, e.g. uuid.UUID, which fails on
+	// malformed values.
+	ToEntUUID string
+	UUIDType  string
+	// TimeFormat is the pb representation of a time field. ToEntTime names the
+	// generated function converting the pb value back, which fails on malformed
+	// values when ToEntTimeReturnsError is set.
+	TimeFormat            TimeFormat
+	ToEntTime             string
+	ToEntTimeReturnsError bool
+	// ToProtoJSON and ToEntJSON name the generated functions encoding the value
+	// of a JSON field to the JSON held by its pb bytes or string, and decoding
+	// it into JSONType. Both fail on values encoding/json rejects.
+	ToProtoJSON string
+	ToEntJSON   string
+	JSONType    string
+	// CloneMessage names the generated function deep-copying the value of an
+	// external message field, or the messages of a repeated one, in both
+	// directions.
+	CloneMessage string
+}
+
+// Options configures the conversions created by NewConverter.
+type Options struct {
+	// TimeFormat is the pb representation of time fields, or empty for the
+	// format matching their pb field type.
+	TimeFormat TimeFormat
+	// CloneMessages deep-copies the values of external message fields instead
+	// of sharing them between the ent and pb values.
+	CloneMessages bool
 }
 
 // NewConverter creates a Converter for the given field mapping and type name.
-func NewConverter(fld *entproto.FieldMappingDescriptor, typeName string) (*Converter, error) {
+func NewConverter(fld *entproto.FieldMappingDescriptor, typeName string, opts Options) (*Converter, error) {
 	out := &Converter{}
 	pbd := fld.PbFieldDescriptor
+	if fld.EntField != nil {
+		if ot, ok := entproto.LookupOtherType(fld.EntField.Type); ok {
+			out.ToProtoFunc = ot.ToProto
+			out.ToEntFunc = ot.ToEnt
+			out.ToEntFuncReturnsError = ot.ToEntReturnsError
+			return out, nil
+		}
+		if !fld.IsEdgeField && fld.EntField.IsTime() {
+			if err := timeConversion(pbd, fld.EntField, opts.TimeFormat, out); err != nil {
+				return nil, err
+			}
+			return out, nil
+		}
+		if !fld.IsEdgeField && fld.EncodesJSON() {
+			// JSON fields without a pb counterpart, annotated with
+			// entproto.EncodeJSON, hold their JSON encoding.
+			switch pbd.GetType() {
+			case dpb.FieldDescriptorProto_TYPE_BYTES:
+				out.ToProtoJSON, out.ToEntJSON = "jsonToBytes", "jsonFromBytes"
+			case dpb.FieldDescriptorProto_TYPE_STRING:
+				out.ToProtoJSON, out.ToEntJSON = "jsonToString", "jsonFromString"
+			}
+			if out.ToEntJSON != "" {
+				out.JSONType = fld.EntField.Type.String()
+				return out, nil
+			}
+		}
+	}
 	switch pbd.GetType() {
 	case dpb.FieldDescriptorProto_TYPE_BOOL, dpb.FieldDescriptorProto_TYPE_STRING,
 		dpb.FieldDescriptorProto_TYPE_BYTES, dpb.FieldDescriptorProto_TYPE_INT32,
@@ -52,10 +136,14 @@ func NewConverter(fld *entproto.FieldMappingDescriptor, typeName string) (*Conve
 		if err := basicTypeConversion(fld.PbFieldDescriptor, fld.EntField, out); err != nil {
 			return nil, err
 		}
+		if fld.EntField != nil && out.ToProtoConversion != "" {
+			out.ToProtoNarrows = narrows(fld.EntField.Type.String(), out.ToProtoConversion)
+		}
 	case dpb.FieldDescriptorProto_TYPE_ENUM:
 		enumName := fld.PbFieldDescriptor.GetEnumType().GetName()
 		method := fmt.Sprintf("ToProto%s_%s", typeName, enumName)
 		out.ToProtoConstructor = method
+		out.ToProtoEnumMap = fmt.Sprintf("toProto%s_%sMap", typeName, enumName)
 	case dpb.FieldDescriptorProto_TYPE_MESSAGE:
 		switch {
 		case fld.IsEdgeField:
@@ -64,14 +152,20 @@ func NewConverter(fld *entproto.FieldMappingDescriptor, typeName string) (*Conve
 			}
 		default:
 			// External proto message (via entproto.MessageField on a JSON column):
-			// ent and pb both store the same generated Go struct, so the
-			// conversion is identity. An empty Converter lets the template emit
-			// a direct assignment. The ent-field switch below would otherwise
-			// reject the non-scalar JSON type, so return early.
+			// ent and pb both store the same generated Go struct, so the value
+			// is cloned, or assigned directly when messages are not cloned.
+			// The ent-field switch below would otherwise reject the non-scalar
+			// JSON type, so return early.
+			if opts.CloneMessages {
+				out.CloneMessage = "cloneMessage"
+				if pbd.IsRepeated() {
+					out.CloneMessage = "cloneMessages"
+				}
+			}
 			return out, nil
 		}
 	default:
-		return nil, fmt.Errorf("entproto: no mapping for pb field type %q", pbd.GetType())
+		return nil, unsupported("entproto: no mapping for pb field type %q", pbd.GetType())
 	}
 	efld := fld.EntField
 	if fld.IsEdgeField {
@@ -79,6 +173,20 @@ func NewConverter(fld *entproto.FieldMappingDescriptor, typeName string) (*Conve
 	}
 
 	switch {
+	case !fld.IsEdgeField && efld.IsUUID():
+		// UUIDs, including UUID IDs, are formatted as strings or stored as
+		// their 16 bytes. The zero UUID maps to the empty pb value.
+		switch pbd.GetType() {
+		case dpb.FieldDescriptorProto_TYPE_STRING:
+			out.ToProtoConstructor = "uuidToString"
+			out.ToEntUUID = "uuidFromString"
+		case dpb.FieldDescriptorProto_TYPE_BYTES:
+			out.ToProtoConstructor = "uuidToBytes"
+			out.ToEntUUID = "uuidFromBytes"
+		default:
+			return nil, unsupported("entproto: no mapping from UUID field %q to pb field type %q", efld.Name, pbd.GetType())
+		}
+		out.UUIDType = efld.Type.String()
 	case fld.IsIDField || (fld.EntField != nil && strings.ToLower(fld.EntField.Name) == "id"):
 		// ID field - use the ent field type directly
 		// For ID fields, Type.Ident may be empty, so use Type.Type.String() instead
@@ -110,29 +218,156 @@ func NewConverter(fld *entproto.FieldMappingDescriptor, typeName string) (*Conve
 	case efld.IsBool(), efld.IsBytes(), efld.IsString():
 	case efld.Type.Numeric():
 		out.ToEntConversion = efld.Type.String()
-	case efld.IsTime():
-		out.ToProtoConversion = "int64"
-		out.ToProtoConversionModifier = ".Unix()"
-		out.ToEntConversion = "time.Unix"
-		out.ToEntConversionArg = "0"
 	case efld.IsEnum():
 		enumName := fld.PbFieldDescriptor.GetEnumType().GetName()
 		method := fmt.Sprintf("ToEnt%s_%s", typeName, enumName)
 		out.ToEntConstructor = method
+		out.ToEntEnumMap = fmt.Sprintf("toEnt%s_%sMap", typeName, enumName)
 	case efld.IsJSON():
 		switch efld.Type.Ident {
 		case "[]string":
 		case "[]int32", "[]int64", "[]uint32", "[]uint64":
 			out.ToProtoConversion = ""
 		default:
-			return nil, fmt.Errorf("entproto: no mapping to ent field type %q", efld.Type.ConstName())
+			return nil, unsupported("entproto: no mapping to ent field type %q", efld.Type.ConstName())
 		}
 	default:
-		return nil, fmt.Errorf("entproto: no mapping to ent field type %q", efld.Type.ConstName())
+		return nil, unsupported("entproto: no mapping to ent field type %q", efld.Type.ConstName())
+	}
+	if out.ToEntConversion != "" {
+		out.ToEntNarrows = narrows(pbGoTypes[pbd.GetType()], out.ToEntConversion)
 	}
 	return out, nil
 }
 
+// TimeFormat is the pb representation of an ent time field.
+type TimeFormat string
+
+const (
+	TimeUnix      TimeFormat = "unix"
+	TimeUnixMilli TimeFormat = "unix_milli"
+	TimeUnixMicro TimeFormat = "unix_micro"
+	TimeUnixNano  TimeFormat = "unix_nano"
+	TimeRFC3339   TimeFormat = "rfc3339"
+	TimeTimestamp TimeFormat = "timestamp"
+)
+
+// timeFuncSuffixes holds the suffix of the generated functions converting each
+// TimeFormat, e.g. timeToUnixMilli and timeFromUnixMilli.
+var timeFuncSuffixes = map[TimeFormat]string{
+	TimeUnix:      "Unix",
+	TimeUnixMilli: "UnixMilli",
+	TimeUnixMicro: "UnixMicro",
+	TimeUnixNano:  "UnixNano",
+	TimeRFC3339:   "RFC3339",
+	TimeTimestamp: "Timestamp",
+}
+
+// Valid reports whether f is a known TimeFormat.
+func (f TimeFormat) Valid() bool {
+	_, ok := timeFuncSuffixes[f]
+	return ok
+}
+
+// IsUnix reports whether f is an integer Unix time.
+func (f TimeFormat) IsUnix() bool {
+	return strings.HasPrefix(string(f), string(TimeUnix))
+}
+
+// timestampTypeName is the type name of google.protobuf.Timestamp fields.
+const timestampTypeName = ".google.protobuf.Timestamp"
+
+// MatchingTimeFormat returns the TimeFormat matching the type of the pb field
+// pbd: Unix seconds for int64, RFC 3339 for string and google.protobuf.Timestamp,
+// or "" when time fields have no mapping to it.
+func MatchingTimeFormat(pbd *desc.FieldDescriptor) TimeFormat {
+	switch pbd.GetType() {
+	case dpb.FieldDescriptorProto_TYPE_INT64, dpb.FieldDescriptorProto_TYPE_SINT64, dpb.FieldDescriptorProto_TYPE_SFIXED64:
+		return TimeUnix
+	case dpb.FieldDescriptorProto_TYPE_STRING:
+		return TimeRFC3339
+	case dpb.FieldDescriptorProto_TYPE_MESSAGE:
+		if pbd.AsFieldDescriptorProto().GetTypeName() == timestampTypeName {
+			return TimeTimestamp
+		}
+	}
+	return ""
+}
+
+// Fits reports whether time fields convert in f to the pb field pbd.
+func (f TimeFormat) Fits(pbd *desc.FieldDescriptor) bool {
+	matching := MatchingTimeFormat(pbd)
+	return matching != "" && (f == matching || f.IsUnix() && matching.IsUnix())
+}
+
+// timeConversion sets the conversion of the time field efld to the pb field pbd
+// in format, or in the format matching the pb field type when it is empty.
+func timeConversion(pbd *desc.FieldDescriptor, efld *gen.Field, format TimeFormat, out *Converter) error {
+	matching := MatchingTimeFormat(pbd)
+	if matching == "" {
+		return unsupported("entproto: no mapping from time field %q to pb field type %q", efld.Name, pbd.GetType())
+	}
+	switch {
+	case format == "":
+		format = matching
+	case !format.Valid():
+		return fmt.Errorf("entconv: unknown time format %q of field %q", format, efld.Name)
+	case !format.Fits(pbd):
+		return fmt.Errorf("entconv: time format %q of field %q does not match its pb field type %q", format, efld.Name, pbd.GetType())
+	}
+	out.TimeFormat = format
+	out.ToProtoConstructor = "timeTo" + timeFuncSuffixes[format]
+	out.ToEntTime = "timeFrom" + timeFuncSuffixes[format]
+	out.ToEntTimeReturnsError = format == TimeRFC3339
+	return nil
+}
+
+// pbGoTypes maps the protobuf integer types to their Go type.
+var pbGoTypes = map[dpb.FieldDescriptorProto_Type]string{
+	dpb.FieldDescriptorProto_TYPE_INT32:    "int32",
+	dpb.FieldDescriptorProto_TYPE_SINT32:   "int32",
+	dpb.FieldDescriptorProto_TYPE_SFIXED32: "int32",
+	dpb.FieldDescriptorProto_TYPE_INT64:    "int64",
+	dpb.FieldDescriptorProto_TYPE_SINT64:   "int64",
+	dpb.FieldDescriptorProto_TYPE_SFIXED64: "int64",
+	dpb.FieldDescriptorProto_TYPE_UINT32:   "uint32",
+	dpb.FieldDescriptorProto_TYPE_FIXED32:  "uint32",
+	dpb.FieldDescriptorProto_TYPE_UINT64:   "uint64",
+	dpb.FieldDescriptorProto_TYPE_FIXED64:  "uint64",
+}
+
+// PbGoType returns the Go type of the pb integer type t, or "" when t is not an
+// integer type.
+func PbGoType(t dpb.FieldDescriptorProto_Type) string {
+	return pbGoTypes[t]
+}
+
+// integerBits holds the size of the Go integer types. int and uint are 32 bits
+// wide on some platforms, so they are listed with their minimum and maximum size.
+var integerBits = map[string][2]int{
+	"int8": {8, 8}, "int16": {16, 16}, "int32": {32, 32}, "int64": {64, 64}, "int": {32, 64},
+	"uint8": {8, 8}, "uint16": {16, 16}, "uint32": {32, 32}, "uint64": {64, 64}, "uint": {32, 64},
+}
+
+// narrows reports whether converting the integer type from to the integer type
+// to may overflow on some platform. It is false when either is not an integer.
+func narrows(from, to string) bool {
+	fromBits, ok1 := integerBits[from]
+	toBits, ok2 := integerBits[to]
+	if !ok1 || !ok2 || from == to {
+		return false
+	}
+	fromSigned, toSigned := !strings.HasPrefix(from, "u"), !strings.HasPrefix(to, "u")
+	switch {
+	case fromSigned && !toSigned:
+		return true
+	case !fromSigned && toSigned:
+		return toBits[0] <= fromBits[1]
+	default:
+		return toBits[0] < fromBits[1]
+	}
+}
+
 // Supported value scanner types (https://golang.org/pkg/database/sql/driver/#Value): [int64, float64, bool, []byte, string, time.Time]
 func basicTypeConversion(md *desc.FieldDescriptor, entField *gen.Field, conv *Converter) error {
 	switch md.GetType() {
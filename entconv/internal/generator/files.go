@@ -0,0 +1,284 @@
+package generator
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// SyncDir writes files, keyed by their names, to dir. Files whose content is
+// unchanged are not rewritten, so their modification times are kept, and the
+// generated files of dir missing from files are removed.
+func SyncDir(dir string, files map[string][]byte) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("creating output directory: %w", err)
+	}
+	for _, name := range sortedNames(files) {
+		p := filepath.Join(dir, name)
+		if old, err := os.ReadFile(p); err == nil && bytes.Equal(old, files[name]) {
+			continue
+		}
+		if err := os.WriteFile(p, files[name], 0644); err != nil {
+			return fmt.Errorf("writing %s: %w", name, err)
+		}
+	}
+	stale, err := staleFiles(dir, files)
+	if err != nil {
+		return err
+	}
+	for _, name := range stale {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			return fmt.Errorf("removing stale %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// FileDiff is a generated file whose content on disk is out of date.
+type FileDiff struct {
+	// Path is the path of the file in its output directory.
+	Path string
+	// Diff is a unified diff from the file on disk to the generated one. A
+	// missing file is diffed from /dev/null, and a stale one to it.
+	Diff string
+}
+
+// DiffDir compares the files, keyed by their names, with the generated files
+// of dir without writing them, and returns the files SyncDir would write or
+// remove.
+func DiffDir(dir string, files map[string][]byte) ([]FileDiff, error) {
+	var diffs []FileDiff
+	for _, name := range sortedNames(files) {
+		p := filepath.Join(dir, name)
+		old, err := os.ReadFile(p)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			diffs = append(diffs, FileDiff{Path: p, Diff: unifiedDiff("/dev/null", p, nil, files[name])})
+		case err != nil:
+			return nil, fmt.Errorf("reading %s: %w", name, err)
+		case !bytes.Equal(old, files[name]):
+			diffs = append(diffs, FileDiff{Path: p, Diff: unifiedDiff(p, p, old, files[name])})
+		}
+	}
+	stale, err := staleFiles(dir, files)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range stale {
+		p := filepath.Join(dir, name)
+		old, err := os.ReadFile(p)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", name, err)
+		}
+		diffs = append(diffs, FileDiff{Path: p, Diff: unifiedDiff(p, "/dev/null", old, nil)})
+	}
+	return diffs, nil
+}
+
+// staleFiles returns the names of the generated .go files of dir, those
+// starting with Header, missing from files. A missing dir has none.
+func staleFiles(dir string, files map[string][]byte) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading output directory: %w", err)
+	}
+	var stale []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if _, ok := files[name]; ok || !entry.Type().IsRegular() || !strings.HasSuffix(name, ".go") {
+			continue
+		}
+		generated, err := isGenerated(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		if generated {
+			stale = append(stale, name)
+		}
+	}
+	return stale, nil
+}
+
+// isGenerated reports whether the first line of the file name is Header.
+func isGenerated(name string) (bool, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	line, err := bufio.NewReader(f).ReadString('\n')
+	if err != nil && line == "" {
+		return false, nil
+	}
+	return strings.TrimRight(line, "\r\n") == Header, nil
+}
+
+func sortedNames(files map[string][]byte) []string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// diffContext is the number of unchanged lines around the changes of a hunk.
+const diffContext = 3
+
+// unifiedDiff returns a unified diff of the lines of a and b, labelled
+// fromName and toName.
+func unifiedDiff(fromName, toName string, a, b []byte) string {
+	x, y := splitLines(a), splitLines(b)
+	ops := diffLines(x, y)
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", fromName, toName)
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+		// Extend the hunk over the changes closer than twice the context.
+		start := max(i-diffContext, 0)
+		end := i
+		for j := i; j < len(ops); j++ {
+			if ops[j].kind != ' ' {
+				end = j + 1
+			} else if j-end >= 2*diffContext {
+				break
+			}
+		}
+		end = min(end+diffContext, len(ops))
+
+		var oldLen, newLen int
+		for _, op := range ops[start:end] {
+			if op.kind != '+' {
+				oldLen++
+			}
+			if op.kind != '-' {
+				newLen++
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(ops[start].x, oldLen), hunkRange(ops[start].y, newLen))
+		for _, op := range ops[start:end] {
+			out.WriteByte(op.kind)
+			out.WriteString(op.line)
+			if !strings.HasSuffix(op.line, "\n") {
+				out.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		i = end
+	}
+	return out.String()
+}
+
+// hunkRange formats the range of a hunk starting after the line before, with
+// n lines.
+func hunkRange(before, n int) string {
+	if n == 0 {
+		return fmt.Sprintf("%d,0", before)
+	}
+	return fmt.Sprintf("%d,%d", before+1, n)
+}
+
+// diffOp is a line of an edit script: ' ' keeps it, '-' removes it and '+'
+// adds it. x and y are the number of lines of a and b before it.
+type diffOp struct {
+	kind byte
+	line string
+	x, y int
+}
+
+// maxDiffCells bounds the size of the table diffLines builds over the lines
+// between the common prefix and suffix, so that checking large files takes
+// bounded memory.
+const maxDiffCells = 1 << 21
+
+// diffLines returns an edit script turning x into y along their longest
+// common subsequence. When the changed lines exceed maxDiffCells, they are
+// all removed and added back instead.
+func diffLines(x, y []string) []diffOp {
+	pre := 0
+	for pre < len(x) && pre < len(y) && x[pre] == y[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(x)-pre && suf < len(y)-pre && x[len(x)-1-suf] == y[len(y)-1-suf] {
+		suf++
+	}
+	var ops []diffOp
+	for i := range pre {
+		ops = append(ops, diffOp{kind: ' ', line: x[i], x: i, y: i})
+	}
+	ops = append(ops, diffChanged(x[pre:len(x)-suf], y[pre:len(y)-suf], pre)...)
+	for k := suf; k > 0; k-- {
+		i, j := len(x)-k, len(y)-k
+		ops = append(ops, diffOp{kind: ' ', line: x[i], x: i, y: j})
+	}
+	return ops
+}
+
+// diffChanged returns the edit script of the lines x and y following the
+// common prefix of off lines.
+func diffChanged(x, y []string, off int) []diffOp {
+	var ops []diffOp
+	if (len(x)+1)*(len(y)+1) > maxDiffCells {
+		for i, line := range x {
+			ops = append(ops, diffOp{kind: '-', line: line, x: off + i, y: off})
+		}
+		for j, line := range y {
+			ops = append(ops, diffOp{kind: '+', line: line, x: off + len(x), y: off + j})
+		}
+		return ops
+	}
+	// lcs[i][j] is the length of the longest common subsequence of x[i:] and y[j:].
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			ops = append(ops, diffOp{kind: ' ', line: x[i], x: off + i, y: off + j})
+			i++
+			j++
+		case j == len(y) || i < len(x) && lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{kind: '-', line: x[i], x: off + i, y: off + j})
+			i++
+		default:
+			ops = append(ops, diffOp{kind: '+', line: y[j], x: off + i, y: off + j})
+			j++
+		}
+	}
+	return ops
+}
+
+func splitLines(b []byte) []string {
+	if len(b) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(b), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
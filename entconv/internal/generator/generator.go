@@ -3,12 +3,15 @@ package generator
 import (
 	"bytes"
 	"embed"
+	"errors"
 	"fmt"
 	"go/format"
 	"io"
 	"io/fs"
 	"os"
 	"path"
+	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -20,20 +23,27 @@ import (
 	"golang.org/x/tools/imports"
 )
 
-// templateSource caches the embedded template text to avoid repeated file I/O.
-var (
-	templateSource     string
-	templateSourceOnce sync.Once
-	templateSourceErr  error
-)
-
-// ProtoMessage represents a parsed proto message from .pb.go file.
+// ProtoMessage represents a proto message struct loaded from its Go package.
 type ProtoMessage struct {
-	Name   string
-	Fields []ProtoField
+	Name string
+	// GoPackage is the import path of the package declaring the message, empty
+	// when the files were loaded outside a module.
+	GoPackage string
+	Fields    []ProtoField
+}
+
+// Field returns the field of the message named name.
+func (m *ProtoMessage) Field(name string) (ProtoField, bool) {
+	for _, f := range m.Fields {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return ProtoField{}, false
 }
 
-// ProtoField represents a field in a proto message.
+// ProtoField represents a field in a proto message, with its Go type written
+// without package qualifiers.
 type ProtoField struct {
 	Name string
 	Type string
@@ -44,27 +54,127 @@ type TypeInfo struct {
 	MessageName string
 	Message     *ProtoMessage
 	Type        *gen.Type
+	// ProtoPackage is the proto package of the message.
+	ProtoPackage string
+	// GoPackage is the import path of the Go package of the message, and
+	// ProtoAlias its import alias. ProtoAlias is empty when the converters are
+	// generated into that package.
+	GoPackage  string
+	ProtoAlias string
+	// Subset reports whether the message is a further message of the type
+	// holding some of its fields, e.g. UserSummary. Only the fields it declares
+	// are converted, by converters named after the message.
+	Subset bool
+}
+
+// ConvName returns the name the converters of the type info are named after.
+func (t TypeInfo) ConvName() string {
+	if t.Subset {
+		return t.MessageName
+	}
+	return t.Type.Name
+}
+
+// FieldFuncs are the user functions converting a single field in place of its
+// built-in conversion.
+type FieldFuncs struct {
+	ToProto           entproto.GoFunc
+	ToEnt             entproto.GoFunc
+	ToEntReturnsError bool
+}
+
+// FieldKey returns the key of the field of an ent type in Generator.FieldConverters.
+func FieldKey(typeName, fieldName string) string {
+	return typeName + "." + fieldName
 }
 
 // Generator handles code generation for Ent <-> Proto converters.
 type Generator struct {
 	EntPackage  string
 	ConvPackage string
-	// ProtoPackagePath is the import path for the proto package.
-	ProtoPackagePath string
-	// ProtoAlias is the alias used for the proto package in generated code.
-	ProtoAlias string
-	Types      []TypeInfo
-	Adapter    *entproto.Adapter
-	Graph      *gen.Graph
+	Types       []TypeInfo
+	// MaxEdgeDepth is the number of edge levels converted below an entity.
+	// Edges are not converted when it is zero.
+	MaxEdgeDepth int
+	// Strict makes the converters return a *ConversionError for enum values
+	// without a counterpart and for integers overflowing their target type.
+	Strict bool
+	// FieldConverters are the user functions overriding the conversion of
+	// single fields, keyed by FieldKey.
+	FieldConverters map[string]FieldFuncs
+	// TimeFormat is the pb representation of the time fields without an entry
+	// in FieldTimeFormats, keyed by FieldKey. An empty format is the one
+	// matching the pb field type.
+	TimeFormat       converter.TimeFormat
+	FieldTimeFormats map[string]converter.TimeFormat
+	// UnixZeroTime makes the converters decode the Unix time 0 to the zero
+	// time instead of the Unix epoch.
+	UnixZeroTime bool
+	// CloneMessages makes the converters deep-copy the values of external
+	// message fields instead of sharing them between ent and pb.
+	CloneMessages bool
+	// Tests makes RenderAll render a test file per type, exercising its
+	// converters, and a test file of shared helpers.
+	Tests bool
+	// AllowSensitive holds the Sensitive fields, keyed by FieldKey, that
+	// ToProto converts. Other Sensitive fields are redacted.
+	AllowSensitive map[string]bool
+	// Templates are parsed after the built-in templates, overriding or adding
+	// named templates.
+	Templates []TemplateFiles
+	// TemplateFuncs are added to the functions of the templates, replacing the
+	// built-in functions of the same name.
+	TemplateFuncs template.FuncMap
+	// Parallelism is the maximum number of files RenderAll renders
+	// concurrently. Values below one mean runtime.GOMAXPROCS(0).
+	Parallelism int
+	Adapter     *entproto.Adapter
+	Graph       *gen.Graph
+	// cache holds the parsed templates and the field maps, shared by the
+	// generators derived from the same New.
+	cache *runCache
 	// nodeIndex provides O(1) lookup for node names
 	nodeIndex map[string]*gen.Type
 	// typeIndex provides O(1) lookup for type info by name
 	typeIndex map[string]*TypeInfo
 }
 
+// runCache holds the state computed once per run and shared by the generators
+// of the run: the templates, read and parsed with the user templates, and the
+// field maps. It is safe for concurrent use.
+type runCache struct {
+	tmplOnce sync.Once
+	tmpl     *template.Template
+	tmplErr  error
+
+	mu        sync.Mutex
+	fieldMaps map[string]*fieldMapEntry
+}
+
+type fieldMapEntry struct {
+	once     sync.Once
+	fieldMap entproto.FieldMap
+	err      error
+}
+
+// TemplateFiles selects template files parsed after the built-in templates,
+// either Patterns of FS, as in template.ParseFS, or the files matching Glob, as
+// in template.ParseGlob.
+type TemplateFiles struct {
+	FS       fs.FS
+	Patterns []string
+	Glob     string
+}
+
+// TypeData is the data of the "entconv/type" template, and of the templates
+// overriding or extending it, rendering the converters of a type.
+type TypeData struct {
+	Generator *Generator
+	TypeInfo  TypeInfo
+}
+
 // New creates a new Generator.
-func New(entPackage, pkg, pkgPath, protoAlias string, types []TypeInfo, adapter *entproto.Adapter, graph *gen.Graph) *Generator {
+func New(entPackage, pkg string, types []TypeInfo, adapter *entproto.Adapter, graph *gen.Graph) *Generator {
 	// Build index for O(1) node lookup
 	nodeIndex := make(map[string]*gen.Type, len(graph.Nodes))
 	for _, node := range graph.Nodes {
@@ -74,47 +184,120 @@ func New(entPackage, pkg, pkgPath, protoAlias string, types []TypeInfo, adapter
 	// Build index for O(1) type info lookup
 	typeIndex := make(map[string]*TypeInfo, len(types))
 	for i := range types {
-		typeIndex[types[i].Type.Name] = &types[i]
+		if !types[i].Subset {
+			typeIndex[types[i].Type.Name] = &types[i]
+		}
 	}
 
 	return &Generator{
-		EntPackage:       entPackage,
-		ConvPackage:      pkg,
-		ProtoPackagePath: pkgPath,
-		ProtoAlias:       protoAlias,
-		Types:            types,
-		Adapter:          adapter,
-		Graph:            graph,
-		nodeIndex:        nodeIndex,
-		typeIndex:        typeIndex,
+		EntPackage:  entPackage,
+		ConvPackage: pkg,
+		Types:       types,
+		Adapter:     adapter,
+		Graph:       graph,
+		cache:       &runCache{fieldMaps: make(map[string]*fieldMapEntry)},
+		nodeIndex:   nodeIndex,
+		typeIndex:   typeIndex,
 	}
 }
 
+// ForTypes returns a generator writing the converters of types into the Go
+// package pkg. Edges to types outside of types are not converted.
+func (g *Generator) ForTypes(pkg string, types []TypeInfo) *Generator {
+	out := New(g.EntPackage, pkg, types, g.Adapter, g.Graph)
+	out.MaxEdgeDepth = g.MaxEdgeDepth
+	out.Strict = g.Strict
+	out.FieldConverters = g.FieldConverters
+	out.TimeFormat = g.TimeFormat
+	out.FieldTimeFormats = g.FieldTimeFormats
+	out.UnixZeroTime = g.UnixZeroTime
+	out.CloneMessages = g.CloneMessages
+	out.Tests = g.Tests
+	out.AllowSensitive = g.AllowSensitive
+	out.Templates = g.Templates
+	out.TemplateFuncs = g.TemplateFuncs
+	out.Parallelism = g.Parallelism
+	out.cache = g.cache
+	return out
+}
+
 //go:embed template/*
 var templates embed.FS
 
-// getTemplate parses a template bound to the current generator's function map.
-func (g *Generator) getTemplate() (*gen.Template, error) {
-	templateSourceOnce.Do(func() {
-		tmplContent, err := fs.ReadFile(templates, "template/converter.tmpl")
-		if err != nil {
-			templateSourceErr = fmt.Errorf("failed to read template: %w", err)
-			return
-		}
-		templateSource = string(tmplContent)
+// getTemplate returns the templates bound to the current generator's function
+// map. They are parsed once per run and cloned for each generator.
+func (g *Generator) getTemplate() (*template.Template, error) {
+	c := g.cache
+	c.tmplOnce.Do(func() {
+		c.tmpl, c.tmplErr = g.parseTemplate()
 	})
-
-	if templateSourceErr != nil {
-		return nil, templateSourceErr
+	if c.tmplErr != nil {
+		return nil, c.tmplErr
+	}
+	tmpl, err := c.tmpl.Clone()
+	if err != nil {
+		return nil, fmt.Errorf("failed to clone template: %w", err)
 	}
+	return tmpl.Funcs(g.templateFuncs()).
+		Funcs(lookupFuncs(tmpl)).
+		Funcs(g.TemplateFuncs), nil
+}
 
-	tmpl, err := gen.NewTemplate("converter").
-		Funcs(g.templateFuncs()).
-		Parse(templateSource)
+// parseTemplate parses the embedded templates, followed by the user templates.
+func (g *Generator) parseTemplate() (*template.Template, error) {
+	source, err := fs.ReadFile(templates, "template/converter.tmpl")
 	if err != nil {
+		return nil, fmt.Errorf("failed to read template: %w", err)
+	}
+
+	tmpl := gen.NewTemplate("converter")
+	tmpl.Funcs(g.templateFuncs()).
+		Funcs(lookupFuncs(tmpl.Template)).
+		Funcs(g.TemplateFuncs)
+	if _, err := tmpl.Parse(string(source)); err != nil {
 		return nil, fmt.Errorf("failed to parse template: %w", err)
 	}
-	return tmpl, nil
+	for _, files := range g.Templates {
+		if files.FS != nil {
+			_, err = tmpl.ParseFS(files.FS, files.Patterns...)
+		} else {
+			_, err = tmpl.ParseGlob(files.Glob)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse user templates: %w", err)
+		}
+	}
+	return tmpl.Template, nil
+}
+
+// lookupFuncs returns the template functions of gen.Funcs looking up named
+// templates, bound to tmpl instead of the ent templates.
+func lookupFuncs(tmpl *template.Template) template.FuncMap {
+	return template.FuncMap{
+		"xtemplate": func(name string, v any) (string, error) {
+			var buf bytes.Buffer
+			if err := tmpl.ExecuteTemplate(&buf, name, v); err != nil {
+				return "", err
+			}
+			return buf.String(), nil
+		},
+		"hasTemplate": func(name string) bool {
+			return tmpl.Lookup(name) != nil
+		},
+		"matchTemplate": func(patterns ...string) []string {
+			var names []string
+			for _, t := range tmpl.Templates() {
+				for _, pattern := range patterns {
+					if ok, _ := path.Match(pattern, t.Name()); ok {
+						names = append(names, t.Name())
+						break
+					}
+				}
+			}
+			slices.Sort(names)
+			return names
+		},
+	}
 }
 
 // Generate produces the converter code and writes to the specified output path.
@@ -127,36 +310,124 @@ func (g *Generator) Generate(outputPath string) error {
 	return os.WriteFile(outputPath, buf.Bytes(), 0644)
 }
 
-// GenerateAll generates separate converter files for each type in the output directory.
+// GenerateAll generates separate converter files for each type in the output
+// directory. Files whose content is unchanged are not rewritten, and the
+// generated files of the directory not produced by g, e.g. of deleted types,
+// are removed.
 func (g *Generator) GenerateAll(outputDir string) error {
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		return fmt.Errorf("creating output directory: %w", err)
+	files, err := g.RenderAll(outputDir)
+	if err != nil {
+		return err
 	}
+	return SyncDir(outputDir, files)
+}
 
-	for _, typeInfo := range g.Types {
-		var buf bytes.Buffer
-		if err := g.generateSingleType(&buf, typeInfo); err != nil {
-			return fmt.Errorf("generating %s: %w", typeInfo.Type.Name, err)
-		}
+// RenderAll generates the converter file of each type and the shared file,
+// keyed by their names in outputDir, without writing them. With Tests, the
+// test file of each type and the shared test file are generated as well. The
+// files of the types are rendered by up to Parallelism goroutines.
+func (g *Generator) RenderAll(outputDir string) (map[string][]byte, error) {
+	var (
+		wg       sync.WaitGroup
+		sem      = make(chan struct{}, g.parallelism())
+		names    = make([]string, len(g.Types))
+		contents = make([][]byte, len(g.Types))
+		tests    = make([][]byte, len(g.Types))
+		errs     = make([]error, len(g.Types))
+	)
+	for i, typeInfo := range g.Types {
+		names[i] = strings.ToLower(typeInfo.ConvName()) + ".go"
+		sem <- struct{}{}
+		wg.Go(func() {
+			defer func() { <-sem }()
+			contents[i], errs[i] = g.renderType(path.Join(outputDir, names[i]), typeInfo)
+			if errs[i] == nil && g.Tests {
+				tests[i], errs[i] = g.renderTest(path.Join(outputDir, testFileName(typeInfo)), typeInfo)
+			}
+		})
+	}
+	wg.Wait()
 
-		formatted, err := format.Source(buf.Bytes())
-		if err != nil {
-			return fmt.Errorf("formatting %s: %w", typeInfo.Type.Name, err)
+	files := make(map[string][]byte, 2*len(g.Types)+2)
+	for i, typeInfo := range g.Types {
+		// Report the error of the first type, whichever failed first.
+		if errs[i] != nil {
+			return nil, errs[i]
 		}
-
-		outputPath := fmt.Sprintf("%s/%s.go", outputDir, strings.ToLower(typeInfo.Type.Name))
-		// Use goimports to clean up unused imports
-		optimized, err := imports.Process(outputPath, formatted, nil)
+		files[names[i]] = contents[i]
+		if g.Tests {
+			files[testFileName(typeInfo)] = tests[i]
+		}
+	}
+	shared, err := g.generateSharedFile(outputDir)
+	if err != nil {
+		return nil, err
+	}
+	files[SharedFileName] = shared
+	if g.Tests {
+		sharedTests, err := g.generateSharedTestFile(outputDir)
 		if err != nil {
-			return fmt.Errorf("optimizing imports for %s: %w", typeInfo.Type.Name, err)
+			return nil, err
 		}
+		files[SharedTestFileName] = sharedTests
+	}
+	return files, nil
+}
 
-		if err := os.WriteFile(outputPath, optimized, 0644); err != nil {
-			return fmt.Errorf("writing %s: %w", typeInfo.Type.Name, err)
-		}
+func (g *Generator) parallelism() int {
+	if g.Parallelism > 0 {
+		return g.Parallelism
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// renderType generates, formats and cleans up the imports of the converter
+// file of typeInfo, named filename.
+func (g *Generator) renderType(filename string, typeInfo TypeInfo) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := g.generateSingleType(&buf, typeInfo); err != nil {
+		return nil, fmt.Errorf("generating %s: %w", typeInfo.Type.Name, err)
 	}
 
-	return nil
+	formatted, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting %s: %w", typeInfo.Type.Name, err)
+	}
+
+	// Use goimports to clean up unused imports
+	optimized, err := imports.Process(filename, formatted, nil)
+	if err != nil {
+		return nil, fmt.Errorf("optimizing imports for %s: %w", typeInfo.Type.Name, err)
+	}
+	return optimized, nil
+}
+
+// SharedFileName is the name of the file holding the declarations shared by
+// the converters of a package, written by GenerateAll.
+const SharedFileName = "entconv.go"
+
+// Header is the first line of the generated files. GenerateAll owns the files
+// of its output directory starting with it.
+const Header = "// Code generated by protoc-gen-entconv. DO NOT EDIT."
+
+// generateSharedFile generates the declarations shared by the per-type files.
+func (g *Generator) generateSharedFile(outputDir string) ([]byte, error) {
+	tmpl, err := g.getTemplate()
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := g.writeHeader(&buf, tmpl); err != nil {
+		return nil, err
+	}
+	if err := tmpl.ExecuteTemplate(&buf, "entconv/shared", g); err != nil {
+		return nil, fmt.Errorf("template execution failed: %w", err)
+	}
+	optimized, err := imports.Process(path.Join(outputDir, SharedFileName), buf.Bytes(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("optimizing imports for %s: %w", SharedFileName, err)
+	}
+	return optimized, nil
 }
 
 // GenerateToWriter produces the converter code and writes to the provided io.Writer.
@@ -167,26 +438,14 @@ func (g *Generator) GenerateToWriter(w io.Writer) error {
 
 // generateSingleType generates converter code for a single type.
 func (g *Generator) generateSingleType(w io.Writer, typeInfo TypeInfo) error {
-	// Create a temporary generator with only one type
-	tempGen := &Generator{
-		EntPackage:       g.EntPackage,
-		ConvPackage:      g.ConvPackage,
-		ProtoPackagePath: g.ProtoPackagePath,
-		ProtoAlias:       g.ProtoAlias,
-		Types:            []TypeInfo{typeInfo},
-		Adapter:          g.Adapter,
-		Graph:            g.Graph,
-		nodeIndex:        g.nodeIndex,
-		typeIndex:        g.typeIndex,
-	}
-
+	tempGen := g.forType(typeInfo)
 	tmpl, err := tempGen.getTemplate()
 	if err != nil {
 		return err
 	}
 
 	// Write header with imports
-	if err := tempGen.writeImports(w); err != nil {
+	if err := tempGen.writeHeader(w, tmpl); err != nil {
 		return err
 	}
 
@@ -198,6 +457,33 @@ func (g *Generator) generateSingleType(w io.Writer, typeInfo TypeInfo) error {
 	return nil
 }
 
+// forType returns a temporary generator with only the type typeInfo, sharing
+// the settings and the indexes of g.
+func (g *Generator) forType(typeInfo TypeInfo) *Generator {
+	return &Generator{
+		EntPackage:       g.EntPackage,
+		ConvPackage:      g.ConvPackage,
+		Types:            []TypeInfo{typeInfo},
+		MaxEdgeDepth:     g.MaxEdgeDepth,
+		Strict:           g.Strict,
+		FieldConverters:  g.FieldConverters,
+		TimeFormat:       g.TimeFormat,
+		FieldTimeFormats: g.FieldTimeFormats,
+		UnixZeroTime:     g.UnixZeroTime,
+		CloneMessages:    g.CloneMessages,
+		Tests:            g.Tests,
+		AllowSensitive:   g.AllowSensitive,
+		Templates:        g.Templates,
+		TemplateFuncs:    g.TemplateFuncs,
+		Parallelism:      g.Parallelism,
+		Adapter:          g.Adapter,
+		Graph:            g.Graph,
+		cache:            g.cache,
+		nodeIndex:        g.nodeIndex,
+		typeIndex:        g.typeIndex,
+	}
+}
+
 // generateBody generates the converter code body (with imports) to the writer.
 func (g *Generator) generateBody(w io.Writer) error {
 	tmpl, err := g.getTemplate()
@@ -206,7 +492,7 @@ func (g *Generator) generateBody(w io.Writer) error {
 	}
 
 	// Write header with imports
-	if err := g.writeImports(w); err != nil {
+	if err := g.writeHeader(w, tmpl); err != nil {
 		return err
 	}
 
@@ -214,6 +500,9 @@ func (g *Generator) generateBody(w io.Writer) error {
 	if err := tmpl.Execute(w, g); err != nil {
 		return fmt.Errorf("template execution failed: %w", err)
 	}
+	if err := tmpl.ExecuteTemplate(w, "entconv/shared", g); err != nil {
+		return fmt.Errorf("template execution failed: %w", err)
+	}
 
 	return nil
 }
@@ -224,22 +513,50 @@ func (g *Generator) Imports() []string {
 
 	// Add ent import
 	imp = append(imp, fmt.Sprintf(`ent "%s"`, g.EntPackage))
-
-	// Add proto package import if ProtoPackagePath is specified (for separate package generation)
-	if g.ProtoPackagePath != "" && g.ProtoAlias != "" {
-		imp = append(imp, fmt.Sprintf(`%s "%s"`, g.ProtoAlias, g.ProtoPackagePath))
+	imp = append(imp, `fieldmaskpb "google.golang.org/protobuf/types/known/fieldmaskpb"`)
+	if g.usesClone() {
+		imp = append(imp, `proto "google.golang.org/protobuf/proto"`)
+	}
+	if g.usesTimeFormat(converter.TimeTimestamp) {
+		imp = append(imp, `timestamppb "google.golang.org/protobuf/types/known/timestamppb"`)
 	}
 
-	// Check if any type needs the post package (for enums)
+	// Check if any type needs its ent package (for enums)
 	for _, t := range g.Types {
-		fieldMap, err := g.Adapter.FieldMap(t.Type.Name)
+		// Add the proto package import of the type (for separate package generation)
+		if i := fmt.Sprintf(`%s "%s"`, t.ProtoAlias, t.GoPackage); t.ProtoAlias != "" && !slices.Contains(imp, i) {
+			imp = append(imp, i)
+		}
+		fieldMap, err := g.fieldMap(t.Type.Name)
 		if err != nil {
 			continue
 		}
-		for range fieldMap.Enums() {
+		if len(fieldMap.Enums()) > 0 {
 			enumPkg, _ := g.entEnumPkg(t.Type.Name)
-			if enumPkg != g.EntPackage {
-				imp = append(imp, fmt.Sprintf(`post "%s"`, enumPkg))
+			if i := fmt.Sprintf(`%s "%s"`, path.Base(enumPkg), enumPkg); enumPkg != g.EntPackage && !slices.Contains(imp, i) {
+				imp = append(imp, i)
+			}
+		}
+		// Packages of the user conversion functions of the fields.
+		for _, f := range fieldMap.Fields() {
+			conv, err := g.newConverter(f, t.Type.Name)
+			if err != nil {
+				continue
+			}
+			// Package of the UUID type, e.g. uuid.UUID, or of the JSON type
+			// decoded by name.
+			if ef := f.EntField; (conv.ToEntUUID != "" || conv.ToEntJSON != "") && ef.Type.PkgPath != "" {
+				if i := fmt.Sprintf(`%s "%s"`, ef.Type.PkgName, ef.Type.PkgPath); !slices.Contains(imp, i) {
+					imp = append(imp, i)
+				}
+			}
+			for _, fn := range []entproto.GoFunc{conv.ToProtoFunc, conv.ToEntFunc} {
+				if fn.Name == "" {
+					continue
+				}
+				if i := fmt.Sprintf(`%s "%s"`, g.funcPkgAlias(fn.PkgPath), fn.PkgPath); !slices.Contains(imp, i) {
+					imp = append(imp, i)
+				}
 			}
 		}
 	}
@@ -247,32 +564,37 @@ func (g *Generator) Imports() []string {
 	return imp
 }
 
-// writeImports writes the import block to the output.
-func (g *Generator) writeImports(w io.Writer) error {
-	imports := g.Imports()
-
-	if _, err := w.Write([]byte("// Code generated by protoc-gen-entconv. DO NOT EDIT.\n")); err != nil {
-		return err
-	}
-	if _, err := w.Write([]byte("package " + g.ConvPackage + "\n\n")); err != nil {
+// writeHeader writes the generated code header, followed by the package clause
+// and the import block rendered by the "entconv/header" template.
+func (g *Generator) writeHeader(w io.Writer, tmpl *template.Template) error {
+	if _, err := io.WriteString(w, Header+"\n"); err != nil {
 		return err
 	}
-	if _, err := w.Write([]byte("import (\n")); err != nil {
-		return err
-	}
-	for _, imp := range imports {
-		if _, err := w.Write([]byte("\t" + imp + "\n")); err != nil {
-			return err
-		}
-	}
-	if _, err := w.Write([]byte(")\n\n")); err != nil {
-		return err
+	if err := tmpl.ExecuteTemplate(w, "entconv/header", g); err != nil {
+		return fmt.Errorf("template execution failed: %w", err)
 	}
 	return nil
 }
 
 func (g *Generator) FieldMap(typeName string) (entproto.FieldMap, error) {
-	return g.Adapter.FieldMap(typeName)
+	return g.fieldMap(typeName)
+}
+
+// fieldMap returns the field map of the ent type typeName, computed once per
+// run. The field map is shared and must not be modified.
+func (g *Generator) fieldMap(typeName string) (entproto.FieldMap, error) {
+	c := g.cache
+	c.mu.Lock()
+	e, ok := c.fieldMaps[typeName]
+	if !ok {
+		e = &fieldMapEntry{}
+		c.fieldMaps[typeName] = e
+	}
+	c.mu.Unlock()
+	e.once.Do(func() {
+		e.fieldMap, e.err = g.Adapter.FieldMap(typeName)
+	})
+	return e.fieldMap, e.err
 }
 
 func (g *Generator) templateFuncs() template.FuncMap {
@@ -292,9 +614,81 @@ func (g *Generator) templateFuncs() template.FuncMap {
 		"statusErr":           g.statusErr,
 		"statusErrf":          g.statusErrf,
 		"getFieldMap":         g.getFieldMap,
+		"messageFieldMap":     g.messageFieldMap,
 		"protoIdent":          g.protoIdent,
 		"entPackageIdent":     g.entPackageIdent,
+		"isSet":               g.isSet,
+		"strict":              func() bool { return g.Strict },
+		"unixZeroTime":        func() bool { return g.UnixZeroTime },
+		"toProtoReturnsError": g.toProtoReturnsError,
+		"toEntReturnsError":   g.toEntReturnsError,
+		"goFunc":              g.goFunc,
+		"convertibleEdges":    g.convertibleEdges,
+		"redacted":            g.redacted,
+		"redactedFields":      g.redactedFields,
+		"pbOneOfField":        pbOneOfField,
+		"typeData":            g.typeData,
+		"usesUUID":            g.usesUUID,
+		"usesTimeFormat":      g.usesTimeFormat,
+		"usesJSON":            g.usesJSON,
+		"usesClone":           g.usesClone,
+		"usesOneOfs":          g.usesOneOfs,
+		"testRandom":          g.testRandom,
+		"testPresence":        g.testPresence,
+		"testEqual":           g.testEqual,
+	}
+}
+
+// usesUUID reports whether the converters of the types parse UUID fields.
+func (g *Generator) usesUUID() bool {
+	return g.anyConverter(func(c *converter.Converter) bool { return c.ToEntUUID != "" })
+}
+
+// usesJSON reports whether the converters of the types encode JSON fields.
+func (g *Generator) usesJSON() bool {
+	return g.anyConverter(func(c *converter.Converter) bool { return c.ToEntJSON != "" })
+}
+
+// usesClone reports whether the converters of the types clone external
+// message fields.
+func (g *Generator) usesClone() bool {
+	return g.anyConverter(func(c *converter.Converter) bool { return c.CloneMessage != "" })
+}
+
+// usesOneOfs reports whether the messages of the types have oneofs.
+func (g *Generator) usesOneOfs() bool {
+	for _, t := range g.Types {
+		if fieldMap, err := g.messageFieldMap(t); err == nil && len(fieldMap.OneOfs()) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// usesTimeFormat reports whether the converters of the types convert time
+// fields in format.
+func (g *Generator) usesTimeFormat(format converter.TimeFormat) bool {
+	return g.anyConverter(func(c *converter.Converter) bool { return c.TimeFormat == format })
+}
+
+// anyConverter reports whether the converter of a field of the types satisfies f.
+func (g *Generator) anyConverter(f func(*converter.Converter) bool) bool {
+	for _, t := range g.Types {
+		fieldMap, err := g.messageFieldMap(t)
+		if err != nil {
+			continue
+		}
+		for _, fld := range fieldMap.Fields() {
+			if conv, err := g.newConverter(fld, t.Type.Name); err == nil && f(conv) {
+				return true
+			}
+		}
 	}
+	return false
+}
+
+func (g *Generator) typeData(t TypeInfo) TypeData {
+	return TypeData{Generator: g, TypeInfo: t}
 }
 
 func (g *Generator) pascal(s string) string {
@@ -321,7 +715,7 @@ func (g *Generator) statusErrf(code, format string, args ...string) string {
 }
 
 func (g *Generator) getFieldMap(typeName string) (entproto.FieldMap, error) {
-	return g.Adapter.FieldMap(typeName)
+	return g.fieldMap(typeName)
 }
 
 func (g *Generator) entIdent(subpath string, ident string) string {
@@ -332,13 +726,101 @@ func (g *Generator) entIdent(subpath string, ident string) string {
 	return pkgName + "." + ident
 }
 
-func (g *Generator) protoIdent(ident string) string {
-	if g.ProtoAlias != "" {
-		return g.ProtoAlias + "." + ident
+// protoIdent qualifies ident, declared in the proto Go package of the message
+// of t, with the import alias of that package.
+func (g *Generator) protoIdent(t TypeInfo, ident string) string {
+	if t.ProtoAlias != "" {
+		return t.ProtoAlias + "." + ident
 	}
 	return ident
 }
 
+// messageFieldMap returns the field map of the message of t, without the
+// fields whose ent and pb types have no conversion.
+func (g *Generator) messageFieldMap(t TypeInfo) (entproto.FieldMap, error) {
+	fm, err := g.fieldMap(t.Type.Name)
+	if err != nil {
+		return nil, err
+	}
+	fm = subsetFieldMap(fm, t)
+	unsupported := g.unsupportedFields(fm, t.Type.Name)
+	if len(unsupported) == 0 {
+		return fm, nil
+	}
+	out := make(entproto.FieldMap, len(fm))
+	for name, f := range fm {
+		out[name] = f
+	}
+	for _, u := range unsupported {
+		delete(out, u.Field.PbFieldDescriptor.GetName())
+	}
+	return out, nil
+}
+
+// UnsupportedField is a field whose ent and pb types have no conversion, left
+// out of the converters.
+type UnsupportedField struct {
+	Field *entproto.FieldMappingDescriptor
+	Err   error
+}
+
+// UnsupportedFields returns the fields of the message of t whose ent and pb
+// types have no conversion, sorted by pb field name. The ID is never left out:
+// the generation fails on its conversion instead, as on other errors, e.g. a
+// time format not matching the pb field type.
+func (g *Generator) UnsupportedFields(t TypeInfo) ([]UnsupportedField, error) {
+	fm, err := g.fieldMap(t.Type.Name)
+	if err != nil {
+		return nil, err
+	}
+	return g.unsupportedFields(subsetFieldMap(fm, t), t.Type.Name), nil
+}
+
+func (g *Generator) unsupportedFields(fm entproto.FieldMap, typeName string) []UnsupportedField {
+	var out []UnsupportedField
+	for _, f := range fm.Fields() {
+		if f.EntField == nil || f.IsIDField {
+			continue
+		}
+		var unsupported *converter.UnsupportedError
+		if _, err := g.newConverter(f, typeName); errors.As(err, &unsupported) {
+			out = append(out, UnsupportedField{Field: f, Err: err})
+		}
+	}
+	return out
+}
+
+// MessageFieldMap returns the field map of the type of t, restricted for subset
+// messages to the fields and oneofs the message declares.
+func MessageFieldMap(adapter *entproto.Adapter, t TypeInfo) (entproto.FieldMap, error) {
+	fm, err := adapter.FieldMap(t.Type.Name)
+	if err != nil {
+		return nil, err
+	}
+	return subsetFieldMap(fm, t), nil
+}
+
+// subsetFieldMap restricts fm, the field map of the type of t, to the fields
+// and oneofs of the message of t when it is a subset message. The fields of
+// other messages are validated against their Go struct instead, a missing one
+// failing generation with a ProtoFieldMismatchError.
+func subsetFieldMap(fm entproto.FieldMap, t TypeInfo) entproto.FieldMap {
+	if !t.Subset {
+		return fm
+	}
+	out := make(entproto.FieldMap, len(fm))
+	for name, f := range fm {
+		pbName := f.PbFieldName()
+		if f.IsOneOfField {
+			pbName = pbOneOfField(f)
+		}
+		if _, ok := t.Message.Field(pbName); ok {
+			out[name] = f
+		}
+	}
+	return out
+}
+
 func (g *Generator) entPackageIdent(typeName string) string {
 	return "ent." + typeName
 }
@@ -350,9 +832,171 @@ func (g *Generator) entEnumPkg(typeName string) (string, error) {
 	return g.EntPackage, nil
 }
 
+// convertibleEdges returns the edges of the field map whose target type has
+// converters generated, unless edge conversion is disabled.
+func (g *Generator) convertibleEdges(fieldMap entproto.FieldMap) []*entproto.FieldMappingDescriptor {
+	if g.MaxEdgeDepth <= 0 {
+		return nil
+	}
+	var out []*entproto.FieldMappingDescriptor
+	for _, e := range fieldMap.Edges() {
+		if _, ok := g.typeIndex[e.EntEdge.Type.Name]; ok {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// UnconvertedEdge is an edge of a message left out of the converters.
+type UnconvertedEdge struct {
+	Edge   *entproto.FieldMappingDescriptor
+	Reason string
+}
+
+// UnconvertedEdges returns the edges of the message of t that the converters
+// leave out, sorted by pb field name.
+func (g *Generator) UnconvertedEdges(t TypeInfo) ([]UnconvertedEdge, error) {
+	fm, err := g.messageFieldMap(t)
+	if err != nil {
+		return nil, err
+	}
+	var out []UnconvertedEdge
+	for _, e := range fm.Edges() {
+		switch target := e.EntEdge.Type.Name; {
+		case g.MaxEdgeDepth <= 0:
+			out = append(out, UnconvertedEdge{Edge: e, Reason: "edge conversion is disabled"})
+		case g.typeIndex[target] == nil:
+			out = append(out, UnconvertedEdge{Edge: e, Reason: fmt.Sprintf("no converters are generated for %s", target)})
+		}
+	}
+	return out, nil
+}
+
+// redacted reports whether ToProto leaves out fld of the type t.
+func (g *Generator) redacted(t TypeInfo, fld *entproto.FieldMappingDescriptor) bool {
+	return isRedacted(t, fld, g.AllowSensitive)
+}
+
+// redactedFields returns the fields of the message of t that ToProto leaves out.
+func (g *Generator) redactedFields(t TypeInfo) ([]*entproto.FieldMappingDescriptor, error) {
+	fieldMap, err := g.messageFieldMap(t)
+	if err != nil {
+		return nil, err
+	}
+	return RedactedFields(fieldMap, t, g.AllowSensitive), nil
+}
+
+// RedactedFields returns the fields of fieldMap, the field map of the message
+// of t, that ToProto leaves out: Sensitive fields not allowed in allow, keyed
+// by FieldKey.
+func RedactedFields(fieldMap entproto.FieldMap, t TypeInfo, allow map[string]bool) []*entproto.FieldMappingDescriptor {
+	var out []*entproto.FieldMappingDescriptor
+	for _, f := range fieldMap.Fields() {
+		if isRedacted(t, f, allow) {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+func isRedacted(t TypeInfo, fld *entproto.FieldMappingDescriptor, allow map[string]bool) bool {
+	return fld.EntField != nil && fld.EntField.Sensitive() && !allow[FieldKey(t.Type.Name, fld.EntField.Name)]
+}
+
+// pbOneOfField returns the name of the Go field of the oneof holding fld.
+func pbOneOfField(fld *entproto.FieldMappingDescriptor) string {
+	return (&entproto.OneOfMappingDescriptor{PbOneOfDescriptor: fld.PbFieldDescriptor.GetOneOf()}).PbFieldName()
+}
+
+// goFunc returns the qualified identifier of a user conversion function.
+func (g *Generator) goFunc(f entproto.GoFunc) string {
+	return g.funcPkgAlias(f.PkgPath) + "." + f.Name
+}
+
+// funcPkgAlias returns the import alias of the package of a user conversion
+// function. It is derived from the import path, as the package name is unknown.
+func (g *Generator) funcPkgAlias(pkgPath string) string {
+	alias := []byte(path.Base(pkgPath))
+	for i, c := range alias {
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '_') {
+			alias[i] = '_'
+		}
+	}
+	if len(alias) == 0 || '0' <= alias[0] && alias[0] <= '9' {
+		alias = append([]byte("pkg"), alias...)
+	}
+	s := string(alias)
+	if s == "ent" || slices.ContainsFunc(g.Types, func(t TypeInfo) bool { return t.ProtoAlias == s }) {
+		return s + "conv"
+	}
+	for name := range g.nodeIndex {
+		if strings.ToLower(name) == s {
+			return s + "conv"
+		}
+	}
+	return s
+}
+
+// isSet returns the condition reporting whether the ent value expr of fld is
+// set, i.e. non-nil or different from its zero value. It decides which field of
+// a oneof is selected, and whether an Optional field has a proto3 optional value.
+func (g *Generator) isSet(fld *gen.Field, expr string) (string, error) {
+	switch {
+	case fld.Nillable:
+		return expr + " != nil", nil
+	case fld.IsTime():
+		return "!" + expr + ".IsZero()", nil
+	case fld.IsBool():
+		return expr, nil
+	case fld.IsBytes(), fld.IsJSON():
+		return "len(" + expr + ") > 0", nil
+	case fld.IsString(), fld.IsEnum():
+		return expr + ` != ""`, nil
+	case fld.Type.Numeric():
+		return expr + " != 0", nil
+	case fld.IsUUID():
+		return expr + " != [16]byte{}", nil
+	default:
+		return "", fmt.Errorf("entconv: cannot tell whether field %q of type %q is set, make it Nillable", fld.Name, fld.Type.ConstName())
+	}
+}
+
+// toProtoReturnsError reports whether converting fld to its pb value may fail.
+func (g *Generator) toProtoReturnsError(fld *entproto.FieldMappingDescriptor, typeName string) (bool, error) {
+	conv, err := g.newConverter(fld, typeName)
+	if err != nil {
+		return false, err
+	}
+	return conv.ToProtoJSON != "" || g.Strict && (conv.ToProtoNarrows || conv.ToProtoEnumMap != ""), nil
+}
+
+// toEntReturnsError reports whether converting the pb value of fld to ent may fail.
+func (g *Generator) toEntReturnsError(fld *entproto.FieldMappingDescriptor, typeName string) (bool, error) {
+	conv, err := g.newConverter(fld, typeName)
+	if err != nil {
+		return false, err
+	}
+	return conv.ToEntFuncReturnsError || conv.ToEntUUID != "" || conv.ToEntTimeReturnsError || conv.ToEntJSON != "" || g.Strict && (conv.ToEntNarrows || conv.ToEntEnumMap != ""), nil
+}
+
 func (g *Generator) newConverter(fld *entproto.FieldMappingDescriptor, typeName string) (*converter.Converter, error) {
 	if _, ok := g.typeIndex[typeName]; !ok {
 		return nil, fmt.Errorf("type %q not found", typeName)
 	}
-	return converter.NewConverter(fld, typeName)
+	if fld.EntField != nil {
+		if fn, ok := g.FieldConverters[FieldKey(typeName, fld.EntField.Name)]; ok {
+			return &converter.Converter{
+				ToProtoFunc:           fn.ToProto,
+				ToEntFunc:             fn.ToEnt,
+				ToEntFuncReturnsError: fn.ToEntReturnsError,
+			}, nil
+		}
+	}
+	opts := converter.Options{TimeFormat: g.TimeFormat, CloneMessages: g.CloneMessages}
+	if fld.EntField != nil {
+		if f, ok := g.FieldTimeFormats[FieldKey(typeName, fld.EntField.Name)]; ok {
+			opts.TimeFormat = f
+		}
+	}
+	return converter.NewConverter(fld, typeName, opts)
 }
@@ -0,0 +1,272 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/go-sphere/entc-extensions/entconv/internal/converter"
+	"github.com/go-sphere/entc-extensions/entproto"
+	"golang.org/x/tools/imports"
+	dpb "google.golang.org/protobuf/types/descriptorpb"
+)
+
+// SharedTestFileName is the name of the file holding the helpers shared by the
+// generated tests, written by GenerateAll when Tests is set.
+const SharedTestFileName = "entconv_test.go"
+
+// testFileName returns the name of the test file of typeInfo.
+func testFileName(typeInfo TypeInfo) string {
+	return strings.ToLower(typeInfo.ConvName()) + "_test.go"
+}
+
+// renderTest generates, formats and cleans up the imports of the test file of
+// typeInfo, named filename.
+func (g *Generator) renderTest(filename string, typeInfo TypeInfo) ([]byte, error) {
+	tempGen := g.forType(typeInfo)
+	tmpl, err := tempGen.getTemplate()
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := tempGen.writeTestHeader(&buf, tmpl); err != nil {
+		return nil, err
+	}
+	if err := tmpl.ExecuteTemplate(&buf, "entconv/test", tempGen.typeData(typeInfo)); err != nil {
+		return nil, fmt.Errorf("generating tests of %s: %w", typeInfo.Type.Name, err)
+	}
+	formatted, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting tests of %s: %w", typeInfo.Type.Name, err)
+	}
+	optimized, err := imports.Process(filename, formatted, nil)
+	if err != nil {
+		return nil, fmt.Errorf("optimizing imports for tests of %s: %w", typeInfo.Type.Name, err)
+	}
+	return optimized, nil
+}
+
+// generateSharedTestFile generates the helpers shared by the per-type tests.
+func (g *Generator) generateSharedTestFile(outputDir string) ([]byte, error) {
+	tmpl, err := g.getTemplate()
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := g.writeTestHeader(&buf, tmpl); err != nil {
+		return nil, err
+	}
+	if err := tmpl.ExecuteTemplate(&buf, "entconv/test/shared", g); err != nil {
+		return nil, fmt.Errorf("template execution failed: %w", err)
+	}
+	optimized, err := imports.Process(path.Join(outputDir, SharedTestFileName), buf.Bytes(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("optimizing imports for %s: %w", SharedTestFileName, err)
+	}
+	return optimized, nil
+}
+
+// writeTestHeader writes the generated code header, followed by the package
+// clause and the import block of a test file rendered by the
+// "entconv/test/header" template.
+func (g *Generator) writeTestHeader(w io.Writer, tmpl *template.Template) error {
+	if _, err := io.WriteString(w, Header+"\n"); err != nil {
+		return err
+	}
+	if err := tmpl.ExecuteTemplate(w, "entconv/test/header", g); err != nil {
+		return fmt.Errorf("template execution failed: %w", err)
+	}
+	return nil
+}
+
+// TestImports returns the list of imports needed by the generated tests.
+func (g *Generator) TestImports() []string {
+	imp := []string{`"math/rand/v2"`, `"reflect"`, `"testing"`, `"time"`}
+	for _, i := range append([]string{`proto "google.golang.org/protobuf/proto"`}, g.Imports()...) {
+		if !slices.Contains(imp, i) {
+			imp = append(imp, i)
+		}
+	}
+	return imp
+}
+
+// timePrecisions holds the precision kept by each TimeFormat.
+var timePrecisions = map[converter.TimeFormat]string{
+	converter.TimeUnix:      "time.Second",
+	converter.TimeUnixMilli: "time.Millisecond",
+	converter.TimeUnixMicro: "time.Microsecond",
+	converter.TimeUnixNano:  "time.Nanosecond",
+	converter.TimeRFC3339:   "time.Nanosecond",
+	converter.TimeTimestamp: "time.Nanosecond",
+}
+
+// testRandom returns the expression of a random value of fld, of the ent type
+// typeName, which converts to pb and back unchanged, or "" when the tests leave
+// the field to its zero value: fields converted by user functions, external
+// messages and Go types unknown to the converters.
+func (g *Generator) testRandom(fld *entproto.FieldMappingDescriptor, typeName string) (string, error) {
+	conv, err := g.newConverter(fld, typeName)
+	if err != nil {
+		return "", err
+	}
+	ef, pbd := fld.EntField, fld.PbFieldDescriptor
+	switch {
+	case conv.ToProtoFunc.Name != "" || conv.ToEntFunc.Name != "":
+		return "", nil
+	case conv.TimeFormat != "":
+		// A Nillable pointer to the zero time has no google.protobuf.Timestamp,
+		// and the Unix time 0 decodes to the epoch unless UnixZeroTime is set.
+		allowZero := !ef.Nillable || conv.TimeFormat != converter.TimeTimestamp
+		if conv.TimeFormat.IsUnix() && !g.UnixZeroTime {
+			allowZero = false
+		}
+		return fmt.Sprintf("randomTime(r, %s, %t)", timePrecisions[conv.TimeFormat], allowZero), nil
+	case conv.ToEntUUID != "":
+		return fmt.Sprintf("randomUUID[%s](r)", conv.UUIDType), nil
+	case ef.IsEnum():
+		values := make([]string, len(ef.Enums))
+		for i, e := range ef.Enums {
+			values[i] = strconv.Quote(e.Value)
+		}
+		return fmt.Sprintf("[]%s{%s}[r.IntN(%d)]", ef.Type.String(), strings.Join(values, ", "), len(values)), nil
+	case ef.IsJSON():
+		// Repeated pb fields of JSON slices hold the same Go slice.
+		elem, ok := strings.CutPrefix(ef.Type.Ident, "[]")
+		if !ok || !pbd.IsRepeated() || conv.CloneMessage != "" || pbd.GetType() == dpb.FieldDescriptorProto_TYPE_MESSAGE {
+			return "", nil
+		}
+		if elem == "string" {
+			return "randomSlice(r, randomString)", nil
+		}
+		return fmt.Sprintf("randomSlice(r, func(r *rand.Rand) %s { return %s })", elem, randomNumber(elem, elem)), nil
+	case ef.HasGoType():
+		return "", nil
+	case ef.IsBool():
+		return "r.IntN(2) == 1", nil
+	case ef.IsString():
+		return "randomString(r)", nil
+	case ef.IsBytes():
+		return "randomBytes(r)", nil
+	case ef.Type.Numeric():
+		pbType := converter.PbGoType(pbd.GetType())
+		switch pbd.GetType() {
+		case dpb.FieldDescriptorProto_TYPE_FLOAT:
+			pbType = "float32"
+		case dpb.FieldDescriptorProto_TYPE_DOUBLE:
+			pbType = "float64"
+		}
+		return randomNumber(ef.Type.String(), pbType), nil
+	}
+	return "", nil
+}
+
+// randomNumber returns the expression of a random value of the numeric Go type
+// typ fitting the numeric Go type pbType as well.
+func randomNumber(typ, pbType string) string {
+	convert := func(expr, exprType string) string {
+		if typ == exprType {
+			return expr
+		}
+		return typ + "(" + expr + ")"
+	}
+	if typ == "float32" || typ == "float64" || pbType == "float32" || pbType == "float64" {
+		if typ == "float32" || pbType == "float32" {
+			return convert("r.Float32()", "float32")
+		}
+		return convert("r.Float64()", "float64")
+	}
+	bits, pbBits := intBits(typ), intBits(pbType)
+	if bits == 0 || pbBits == 0 {
+		return ""
+	}
+	n := min(bits, pbBits)
+	signed, pbSigned := !strings.HasPrefix(typ, "u"), !strings.HasPrefix(pbType, "u")
+	if signed && pbSigned {
+		return convert(shift("int64(r.Uint64())", 64-n), "int64")
+	}
+	// Unsigned values of the signed type of either side keep its sign bit clear.
+	if signed || pbSigned {
+		n--
+	}
+	return convert(shift("r.Uint64()", 64-n), "uint64")
+}
+
+// intBits returns the size of the Go integer type typ, with int and uint
+// counted as 32 bits wide to fit on every platform, or 0 for other types.
+func intBits(typ string) int {
+	switch typ {
+	case "int8", "uint8":
+		return 8
+	case "int16", "uint16":
+		return 16
+	case "int32", "uint32", "int", "uint":
+		return 32
+	case "int64", "uint64":
+		return 64
+	}
+	return 0
+}
+
+func shift(expr string, n int) string {
+	if n == 0 {
+		return expr
+	}
+	return fmt.Sprintf("%s >> %d", expr, n)
+}
+
+// testPresence reports whether the pb value of the Nillable field fld, of the
+// ent type typeName, tells nil from a pointer to the zero value.
+func (g *Generator) testPresence(fld *entproto.FieldMappingDescriptor, typeName string) (bool, error) {
+	conv, err := g.newConverter(fld, typeName)
+	if err != nil {
+		return false, err
+	}
+	return fld.PbFieldDescriptor.IsProto3Optional() || conv.TimeFormat == converter.TimeTimestamp, nil
+}
+
+// testEqual returns the condition reporting whether the field fld of the type
+// t holds equal values in the entities want and got, or "" when the tests do
+// not compare it: redacted fields and the fields without a random value, other
+// than external messages.
+func (g *Generator) testEqual(t TypeInfo, fld *entproto.FieldMappingDescriptor, want, got string) (string, error) {
+	if g.redacted(t, fld) {
+		return "", nil
+	}
+	conv, err := g.newConverter(fld, t.Type.Name)
+	if err != nil {
+		return "", err
+	}
+	random, err := g.testRandom(fld, t.Type.Name)
+	if err != nil {
+		return "", err
+	}
+	ef := fld.EntField
+	want, got = want+"."+ef.StructField(), got+"."+ef.StructField()
+	var equal string
+	switch {
+	case conv.ToProtoFunc.Name != "" || conv.ToEntFunc.Name != "":
+		return "", nil
+	case conv.TimeFormat != "":
+		equal = "equalTime"
+	case fld.PbFieldDescriptor.GetType() == dpb.FieldDescriptorProto_TYPE_MESSAGE && fld.PbFieldDescriptor.IsRepeated():
+		equal = "equalMessages"
+	case fld.PbFieldDescriptor.GetType() == dpb.FieldDescriptorProto_TYPE_MESSAGE:
+		equal = "equalMessage"
+	case random == "":
+		return "", nil
+	case !ef.HasGoType() && (ef.Type.String() == "float32" || ef.Type.String() == "float64"):
+		equal = "equalFloat"
+	default:
+		return fmt.Sprintf("reflect.DeepEqual(%s, %s)", want, got), nil
+	}
+	if ef.Nillable {
+		return fmt.Sprintf("equalPtr(%s, %s, %s)", want, got, equal), nil
+	}
+	return fmt.Sprintf("%s(%s, %s)", equal, want, got), nil
+}
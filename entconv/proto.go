@@ -0,0 +1,282 @@
+package entconv
+
+import (
+	"errors"
+	"fmt"
+	"go/types"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/go-sphere/entc-extensions/entconv/internal/generator"
+	"github.com/go-sphere/entc-extensions/entproto"
+	"github.com/jhump/protoreflect/desc"
+	"golang.org/x/tools/go/packages"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// adHocPackage is the path go/packages gives to files loaded outside a module.
+const adHocPackage = "command-line-arguments"
+
+// ProtoFieldMismatchError reports a field accessed by the generated converters
+// that is missing from the Go struct of a proto message, or whose Go type does
+// not match the field descriptor.
+type ProtoFieldMismatchError struct {
+	Message string
+	Field   string
+	// Got is the Go type of the struct field, empty when the field is missing.
+	Got  string
+	Want string
+}
+
+func (e *ProtoFieldMismatchError) Error() string {
+	if e.Got == "" {
+		return fmt.Sprintf("proto message %s has no field %s of type %s", e.Message, e.Field, e.Want)
+	}
+	return fmt.Sprintf("proto message %s: field %s has type %s, want %s", e.Message, e.Field, e.Got, e.Want)
+}
+
+// protoPaths returns the .pb.go files and directories messages are loaded from.
+func protoPaths(opts *Options) []string {
+	var out []string
+	if opts.ProtoFile != "" {
+		out = append(out, opts.ProtoFile)
+	}
+	return append(out, opts.ProtoPaths...)
+}
+
+// loadProtoMessages type-checks the Go packages holding the given .pb.go files
+// or directories and returns their struct types by name. Inside a module a file
+// loads its whole package, so messages may be split across files. Oneof wrapper
+// types are returned as messages as well.
+func loadProtoMessages(paths []string) (map[string][]*generator.ProtoMessage, error) {
+	result := make(map[string][]*generator.ProtoMessage)
+	seen := make(map[string]bool)
+	for _, p := range paths {
+		pkgs, err := loadProtoPackages(p)
+		if err != nil {
+			return nil, err
+		}
+		for _, pkg := range pkgs {
+			key := pkg.ID
+			if pkg.PkgPath == adHocPackage {
+				key = strings.Join(pkg.GoFiles, string(filepath.ListSeparator))
+			}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			if len(pkg.Errors) > 0 {
+				return nil, fmt.Errorf("loading proto package %s: %v", p, pkg.Errors[0])
+			}
+			goPkg := pkg.PkgPath
+			if goPkg == adHocPackage {
+				goPkg = ""
+			}
+			scope := pkg.Types.Scope()
+			for _, name := range scope.Names() {
+				tn, ok := scope.Lookup(name).(*types.TypeName)
+				if !ok {
+					continue
+				}
+				st, ok := tn.Type().Underlying().(*types.Struct)
+				if !ok {
+					continue
+				}
+				msg := &generator.ProtoMessage{Name: name, GoPackage: goPkg}
+				for i := range st.NumFields() {
+					f := st.Field(i)
+					msg.Fields = append(msg.Fields, generator.ProtoField{
+						Name: f.Name(),
+						Type: types.TypeString(f.Type(), func(*types.Package) string { return "" }),
+					})
+				}
+				result[name] = append(result[name], msg)
+			}
+		}
+	}
+	return result, nil
+}
+
+func loadProtoPackages(p string) ([]*packages.Package, error) {
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return nil, fmt.Errorf("loading proto package: %w", err)
+	}
+	cfg := &packages.Config{Mode: packages.NeedName | packages.NeedFiles | packages.NeedTypes}
+	if !info.IsDir() {
+		cfg.Dir = filepath.Dir(abs)
+		return packages.Load(cfg, "file="+abs)
+	}
+	cfg.Dir = abs
+	pkgs, err := packages.Load(cfg, ".")
+	if err != nil || len(pkgs) > 0 {
+		return pkgs, err
+	}
+	// Outside a module, the files of the directory are loaded as an ad-hoc package.
+	files, err := filepath.Glob(filepath.Join(abs, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	files = slices.DeleteFunc(files, func(f string) bool { return strings.HasSuffix(f, "_test.go") })
+	if len(files) == 0 {
+		return nil, fmt.Errorf("loading proto package: no Go files in %s", p)
+	}
+	return packages.Load(cfg, files...)
+}
+
+// resolveProtoMessage returns the message among the loaded messages sharing a
+// name that is declared in the first of the preferred Go packages declaring
+// one, and the import path of its package. A single message is returned even
+// if no preferred package declares it. The import path of messages loaded
+// outside a module is the configured one, or else protoPackagePath.
+func resolveProtoMessage(candidates []*generator.ProtoMessage, configured, goPackageOption, protoPackagePath string) (*generator.ProtoMessage, string, error) {
+	for _, p := range []string{configured, goPackageOption, protoPackagePath} {
+		for _, m := range candidates {
+			if p != "" && m.GoPackage == p {
+				return m, p, nil
+			}
+		}
+	}
+	if len(candidates) == 1 {
+		m := candidates[0]
+		switch {
+		case m.GoPackage != "":
+			return m, m.GoPackage, nil
+		case configured != "":
+			return m, configured, nil
+		default:
+			return m, protoPackagePath, nil
+		}
+	}
+	pkgs := make([]string, 0, len(candidates))
+	for _, m := range candidates {
+		pkgs = append(pkgs, m.GoPackage)
+	}
+	return nil, "", fmt.Errorf("proto message %s is declared in several packages (%s), configure the Go package of its proto package", candidates[0].Name, strings.Join(pkgs, ", "))
+}
+
+// validateProtoMessages checks that the fields accessed by the generated
+// converters exist on the proto structs with the Go type of their descriptor.
+// Messages and enums of the ent types are expected under the name of the
+// matched message.
+func validateProtoMessages(adapter *entproto.Adapter, typesToGenerate []generator.TypeInfo, messages map[string][]*generator.ProtoMessage, edges bool) error {
+	generated := make(map[string]bool, len(typesToGenerate))
+	renames := make(map[string]string, len(typesToGenerate))
+	for _, ti := range typesToGenerate {
+		if ti.Subset {
+			continue
+		}
+		generated[ti.Type.Name] = true
+		if md, err := adapter.GetMessageDescriptor(ti.Type.Name); err == nil {
+			renames[md.GetFullyQualifiedName()] = ti.MessageName
+		}
+	}
+	goType := func(fd *desc.FieldDescriptor) string { return pbGoType(fd, renames) }
+	var errs []error
+	for _, ti := range typesToGenerate {
+		fm, err := generator.MessageFieldMap(adapter, ti)
+		if err != nil {
+			return err
+		}
+		for _, f := range fm.Fields() {
+			if !f.IsOneOfField {
+				errs = append(errs, checkProtoField(ti.Message, f.PbFieldName(), goType(f.PbFieldDescriptor)))
+				continue
+			}
+			oneOf := &entproto.OneOfMappingDescriptor{PbOneOfDescriptor: f.PbFieldDescriptor.GetOneOf()}
+			errs = append(errs, checkProtoField(ti.Message, oneOf.PbFieldName(), "is"+ti.Message.Name+"_"+oneOf.PbFieldName()))
+			wrapperName := ti.MessageName + "_" + f.PbFieldName()
+			wrapper, ok := messages[wrapperName]
+			if !ok {
+				errs = append(errs, fmt.Errorf("proto message %s has no oneof wrapper type %s", ti.Message.Name, wrapperName))
+				continue
+			}
+			w, _, err := resolveProtoMessage(wrapper, ti.GoPackage, "", "")
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			errs = append(errs, checkProtoField(w, f.PbFieldName(), goType(f.PbFieldDescriptor)))
+		}
+		if !edges {
+			continue
+		}
+		for _, e := range fm.Edges() {
+			if generated[e.EntEdge.Type.Name] {
+				errs = append(errs, checkProtoField(ti.Message, e.PbFieldName(), goType(e.PbFieldDescriptor)))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func checkProtoField(msg *generator.ProtoMessage, name, want string) error {
+	if want == "" {
+		return nil
+	}
+	f, ok := msg.Field(name)
+	switch {
+	case !ok:
+		return &ProtoFieldMismatchError{Message: msg.Name, Field: name, Want: want}
+	case f.Type != want:
+		return &ProtoFieldMismatchError{Message: msg.Name, Field: name, Got: f.Type, Want: want}
+	}
+	return nil
+}
+
+var pbScalarGoTypes = map[descriptorpb.FieldDescriptorProto_Type]string{
+	descriptorpb.FieldDescriptorProto_TYPE_BOOL:     "bool",
+	descriptorpb.FieldDescriptorProto_TYPE_STRING:   "string",
+	descriptorpb.FieldDescriptorProto_TYPE_BYTES:    "[]byte",
+	descriptorpb.FieldDescriptorProto_TYPE_INT32:    "int32",
+	descriptorpb.FieldDescriptorProto_TYPE_SINT32:   "int32",
+	descriptorpb.FieldDescriptorProto_TYPE_SFIXED32: "int32",
+	descriptorpb.FieldDescriptorProto_TYPE_INT64:    "int64",
+	descriptorpb.FieldDescriptorProto_TYPE_SINT64:   "int64",
+	descriptorpb.FieldDescriptorProto_TYPE_SFIXED64: "int64",
+	descriptorpb.FieldDescriptorProto_TYPE_UINT32:   "uint32",
+	descriptorpb.FieldDescriptorProto_TYPE_FIXED32:  "uint32",
+	descriptorpb.FieldDescriptorProto_TYPE_UINT64:   "uint64",
+	descriptorpb.FieldDescriptorProto_TYPE_FIXED64:  "uint64",
+	descriptorpb.FieldDescriptorProto_TYPE_FLOAT:    "float32",
+	descriptorpb.FieldDescriptorProto_TYPE_DOUBLE:   "float64",
+}
+
+// pbGoType returns the type protoc-gen-go declares for the field, without
+// package qualifiers, or "" for map fields, which are not checked. renames maps
+// the full names of top-level messages to the name of their Go struct.
+func pbGoType(fd *desc.FieldDescriptor, renames map[string]string) string {
+	t := pbScalarGoTypes[fd.GetType()]
+	switch {
+	case fd.IsMap():
+		return ""
+	case fd.GetEnumType() != nil:
+		t = pbGoName(fd.GetEnumType(), renames)
+	case fd.GetMessageType() != nil:
+		t = "*" + pbGoName(fd.GetMessageType(), renames)
+	}
+	switch {
+	case fd.IsRepeated():
+		return "[]" + t
+	case fd.IsProto3Optional() && fd.GetMessageType() == nil:
+		return "*" + t
+	}
+	return t
+}
+
+// pbGoName returns the Go name protoc-gen-go gives to a message or enum:
+// nested names are joined with underscores.
+func pbGoName(d desc.Descriptor, renames map[string]string) string {
+	pkg := d.GetFile().GetPackage()
+	parts := strings.Split(strings.TrimPrefix(d.GetFullyQualifiedName(), pkg+"."), ".")
+	if name, ok := renames[pkg+"."+parts[0]]; ok {
+		parts[0] = name
+	}
+	return strings.Join(parts, "_")
+}
@@ -0,0 +1,72 @@
+package entconv
+
+import (
+	"io/fs"
+	"maps"
+	"text/template"
+
+	"github.com/go-sphere/entc-extensions/entconv/internal/generator"
+)
+
+// TemplateFiles selects user template files, parsed after the built-in
+// templates of the generated code.
+type TemplateFiles struct {
+	// FS and Patterns select the files of a file system, as in template.ParseFS.
+	FS       fs.FS
+	Patterns []string
+	// Glob selects the files on disk matching a pattern, as in
+	// template.ParseGlob. It is used when FS is nil.
+	Glob string
+}
+
+// WithTemplates parses the template files of fsys matching patterns after the
+// built-in templates. Their named templates override the built-in ones of the
+// same name, or extend the generated code:
+//
+//	entconv/header                 package clause and imports of each file
+//	entconv/type                   converters of a type
+//	entconv/type/<ConvName>        converters of one type, replacing entconv/type
+//	entconv/type/additional/*      code following the converters of each type
+//	entconv/import/additional/*    further import specs of each file
+//	entconv/additional/*           code of the shared entconv.go file
+//
+// The per-type templates are executed with {{ .Generator }} and {{ .TypeInfo }},
+// the others with the generator, e.g. {{ .ConvPackage }}, {{ .EntPackage }} and
+// {{ .Types }}. The README documents their fields.
+//
+// The built-in template functions, gen.Funcs and those of WithTemplateFuncs are
+// available, with xtemplate, hasTemplate and matchTemplate looking up the
+// templates of entconv.
+func WithTemplates(fsys fs.FS, patterns ...string) Option {
+	return func(o *Options) {
+		o.Templates = append(o.Templates, TemplateFiles{FS: fsys, Patterns: patterns})
+	}
+}
+
+// WithTemplateGlob is like WithTemplates, with the template files on disk
+// matching pattern, e.g. "./templates/entconv/*.tmpl".
+func WithTemplateGlob(pattern string) Option {
+	return func(o *Options) {
+		o.Templates = append(o.Templates, TemplateFiles{Glob: pattern})
+	}
+}
+
+// WithTemplateFuncs adds funcs to the functions available to the templates,
+// replacing the built-in functions of the same name. The files are rendered
+// concurrently, so the functions must be safe for concurrent use.
+func WithTemplateFuncs(funcs template.FuncMap) Option {
+	return func(o *Options) {
+		if o.TemplateFuncs == nil {
+			o.TemplateFuncs = make(template.FuncMap, len(funcs))
+		}
+		maps.Copy(o.TemplateFuncs, funcs)
+	}
+}
+
+func generatorTemplates(files []TemplateFiles) []generator.TemplateFiles {
+	out := make([]generator.TemplateFiles, 0, len(files))
+	for _, f := range files {
+		out = append(out, generator.TemplateFiles{FS: f.FS, Patterns: f.Patterns, Glob: f.Glob})
+	}
+	return out
+}
@@ -0,0 +1,17 @@
+package pb
+
+type Profile struct {
+	state  int
+	Id     int64
+	Name   string
+	Secret string
+	SeenAt bool
+	Legacy string
+	Team   *Team
+}
+
+type Team struct {
+	state int
+	Id    int64
+	Name  string
+}
@@ -0,0 +1,42 @@
+package schema
+
+import (
+	"entgo.io/ent"
+	"entgo.io/ent/schema"
+	"entgo.io/ent/schema/edge"
+	"entgo.io/ent/schema/field"
+	"github.com/go-sphere/entc-extensions/entproto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+type Profile struct {
+	ent.Schema
+}
+
+func (Profile) Annotations() []schema.Annotation {
+	return []schema.Annotation{
+		entproto.Message(),
+	}
+}
+
+func (Profile) Fields() []ent.Field {
+	return []ent.Field{
+		field.String("name").
+			Annotations(entproto.Field(2)),
+		field.String("bio").
+			Annotations(entproto.Skip()),
+		field.String("secret").
+			Sensitive().
+			Annotations(entproto.Field(3)),
+		field.Time("seen_at").
+			Annotations(entproto.Field(4, entproto.Type(descriptorpb.FieldDescriptorProto_TYPE_BOOL))),
+	}
+}
+
+func (Profile) Edges() []ent.Edge {
+	return []ent.Edge{
+		edge.To("team", Team.Type).
+			Unique().
+			Annotations(entproto.Field(6)),
+	}
+}
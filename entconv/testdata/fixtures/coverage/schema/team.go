@@ -0,0 +1,25 @@
+package schema
+
+import (
+	"entgo.io/ent"
+	"entgo.io/ent/schema"
+	"entgo.io/ent/schema/field"
+	"github.com/go-sphere/entc-extensions/entproto"
+)
+
+type Team struct {
+	ent.Schema
+}
+
+func (Team) Annotations() []schema.Annotation {
+	return []schema.Annotation{
+		entproto.Message(),
+	}
+}
+
+func (Team) Fields() []ent.Field {
+	return []ent.Field{
+		field.String("name").
+			Annotations(entproto.Field(2)),
+	}
+}
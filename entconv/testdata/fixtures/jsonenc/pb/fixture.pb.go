@@ -0,0 +1,9 @@
+package pb
+
+type Widget struct {
+	Id       int64
+	Settings []byte
+	Labels   string
+	Tags     []string
+	Raw      []byte
+}
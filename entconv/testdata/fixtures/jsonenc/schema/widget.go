@@ -0,0 +1,39 @@
+package schema
+
+import (
+	"encoding/json"
+
+	"entgo.io/ent"
+	"entgo.io/ent/schema"
+	"entgo.io/ent/schema/field"
+	"github.com/go-sphere/entc-extensions/entproto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+type Settings struct {
+	Theme string `json:"theme"`
+	Beta  bool   `json:"beta"`
+}
+
+type Widget struct {
+	ent.Schema
+}
+
+func (Widget) Annotations() []schema.Annotation {
+	return []schema.Annotation{
+		entproto.Message(),
+	}
+}
+
+func (Widget) Fields() []ent.Field {
+	return []ent.Field{
+		field.JSON("settings", Settings{}).
+			Annotations(entproto.Field(2, entproto.EncodeJSON(descriptorpb.FieldDescriptorProto_TYPE_BYTES))),
+		field.JSON("labels", map[string]string{}).
+			Annotations(entproto.Field(3, entproto.EncodeJSON(descriptorpb.FieldDescriptorProto_TYPE_STRING))),
+		field.Strings("tags").
+			Annotations(entproto.Field(4)),
+		field.JSON("raw", json.RawMessage{}).
+			Annotations(entproto.Field(5, entproto.Type(descriptorpb.FieldDescriptorProto_TYPE_BYTES))),
+	}
+}
@@ -0,0 +1,12 @@
+package pb
+
+import (
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type Job struct {
+	Id          int64
+	Timeout     *durationpb.Duration
+	Checkpoints []*timestamppb.Timestamp
+}
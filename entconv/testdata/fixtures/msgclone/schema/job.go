@@ -0,0 +1,29 @@
+package schema
+
+import (
+	"entgo.io/ent"
+	"entgo.io/ent/schema"
+	"entgo.io/ent/schema/field"
+	"github.com/go-sphere/entc-extensions/entproto"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type Job struct {
+	ent.Schema
+}
+
+func (Job) Annotations() []schema.Annotation {
+	return []schema.Annotation{
+		entproto.Message(),
+	}
+}
+
+func (Job) Fields() []ent.Field {
+	return []ent.Field{
+		field.JSON("timeout", &durationpb.Duration{}).
+			Annotations(entproto.MessageField(2, &durationpb.Duration{})),
+		field.JSON("checkpoints", []*timestamppb.Timestamp{}).
+			Annotations(entproto.MessageField(3, &timestamppb.Timestamp{})),
+	}
+}
@@ -0,0 +1,9 @@
+package pb
+
+type AccountEntity_Status int32
+
+type AccountEntity struct {
+	Id     int64
+	Email  string
+	Status AccountEntity_Status
+}
@@ -0,0 +1,31 @@
+package schema
+
+import (
+	"entgo.io/ent"
+	"entgo.io/ent/schema"
+	"entgo.io/ent/schema/field"
+	"github.com/go-sphere/entc-extensions/entproto"
+)
+
+type Account struct {
+	ent.Schema
+}
+
+func (Account) Annotations() []schema.Annotation {
+	return []schema.Annotation{
+		entproto.Message(entproto.MessageName("AccountEntity")),
+	}
+}
+
+func (Account) Fields() []ent.Field {
+	return []ent.Field{
+		field.String("email").
+			Annotations(entproto.Field(2)),
+		field.Enum("status").
+			Values("active", "closed").
+			Annotations(
+				entproto.Field(3),
+				entproto.Enum(map[string]int32{"active": 1, "closed": 2}),
+			),
+	}
+}
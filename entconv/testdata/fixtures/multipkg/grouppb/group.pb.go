@@ -0,0 +1,10 @@
+package groupv1
+
+import userv1 "github.com/go-sphere/entc-extensions/entconv/testdata/fixtures/multipkg/userpb"
+
+type Group struct {
+	Id      int64
+	Name    string
+	Owner   *userv1.User
+	Members []*userv1.User
+}
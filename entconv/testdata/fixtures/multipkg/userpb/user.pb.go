@@ -0,0 +1,6 @@
+package userv1
+
+type User struct {
+	Id   int64
+	Name string
+}
@@ -0,0 +1,18 @@
+package pb
+
+type Payment struct {
+	Id     int64
+	Method isPayment_Method
+}
+
+type isPayment_Method interface {
+	isPayment_Method()
+}
+
+type Payment_CardToken struct {
+	CardToken string
+}
+
+type Payment_WalletId struct {
+	WalletId string
+}
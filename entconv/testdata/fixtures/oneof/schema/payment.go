@@ -0,0 +1,30 @@
+package schema
+
+import (
+	"entgo.io/ent"
+	"entgo.io/ent/schema"
+	"entgo.io/ent/schema/field"
+	"github.com/go-sphere/entc-extensions/entproto"
+)
+
+type Payment struct {
+	ent.Schema
+}
+
+func (Payment) Annotations() []schema.Annotation {
+	return []schema.Annotation{
+		entproto.Message(),
+		entproto.OneOf("method", "card_token", "wallet_id"),
+	}
+}
+
+func (Payment) Fields() []ent.Field {
+	return []ent.Field{
+		field.String("card_token").
+			Optional().
+			Annotations(entproto.Field(2)),
+		field.String("wallet_id").
+			Optional().
+			Annotations(entproto.Field(3)),
+	}
+}
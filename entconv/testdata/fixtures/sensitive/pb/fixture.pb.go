@@ -0,0 +1,8 @@
+package pb
+
+type Account struct {
+	Id           int64
+	Email        string
+	PasswordHash string
+	ApiToken     string
+}
@@ -0,0 +1,14 @@
+package pb
+
+import (
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type Event struct {
+	Id        int64
+	CreatedAt int64
+	UpdatedAt int64
+	StartedAt string
+	EndedAt   *timestamppb.Timestamp
+	DeletedAt *int64
+}
@@ -0,0 +1,39 @@
+package schema
+
+import (
+	"entgo.io/ent"
+	"entgo.io/ent/schema"
+	"entgo.io/ent/schema/field"
+	"github.com/go-sphere/entc-extensions/entproto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type Event struct {
+	ent.Schema
+}
+
+func (Event) Annotations() []schema.Annotation {
+	return []schema.Annotation{
+		entproto.Message(),
+	}
+}
+
+func (Event) Fields() []ent.Field {
+	return []ent.Field{
+		field.Time("created_at").
+			Annotations(entproto.Field(2)),
+		field.Time("updated_at").
+			Annotations(entproto.Field(3)),
+		field.Time("started_at").
+			Annotations(entproto.Field(4, entproto.Type(descriptorpb.FieldDescriptorProto_TYPE_STRING))),
+		field.Time("ended_at").
+			Optional().
+			Nillable().
+			Annotations(entproto.MessageField(5, &timestamppb.Timestamp{})),
+		field.Time("deleted_at").
+			Optional().
+			Nillable().
+			Annotations(entproto.Field(6, entproto.Optional())),
+	}
+}
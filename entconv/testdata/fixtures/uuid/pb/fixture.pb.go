@@ -0,0 +1,7 @@
+package pb
+
+type Device struct {
+	Id       string
+	Token    []byte
+	ParentId *string
+}
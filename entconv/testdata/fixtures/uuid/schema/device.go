@@ -0,0 +1,33 @@
+package schema
+
+import (
+	"entgo.io/ent"
+	"entgo.io/ent/schema"
+	"entgo.io/ent/schema/field"
+	"github.com/go-sphere/entc-extensions/entproto"
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+type Device struct {
+	ent.Schema
+}
+
+func (Device) Annotations() []schema.Annotation {
+	return []schema.Annotation{
+		entproto.Message(),
+	}
+}
+
+func (Device) Fields() []ent.Field {
+	return []ent.Field{
+		field.UUID("id", uuid.UUID{}).
+			Annotations(entproto.Field(1, entproto.Type(descriptorpb.FieldDescriptorProto_TYPE_STRING))),
+		field.UUID("token", uuid.UUID{}).
+			Annotations(entproto.Field(2)),
+		field.UUID("parent_id", uuid.UUID{}).
+			Optional().
+			Nillable().
+			Annotations(entproto.Field(3, entproto.Optional(), entproto.Type(descriptorpb.FieldDescriptorProto_TYPE_STRING))),
+	}
+}
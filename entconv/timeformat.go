@@ -0,0 +1,149 @@
+package entconv
+
+import (
+	"fmt"
+	"strings"
+
+	"entgo.io/ent/entc/gen"
+	"github.com/go-sphere/entc-extensions/entconv/internal/converter"
+	"github.com/go-sphere/entc-extensions/entconv/internal/generator"
+)
+
+// TimeFormat is the pb representation of ent time fields, set with
+// WithTimeFormat and WithFieldTimeFormat. In every format the zero time
+// converts to the zero pb value, 0, "" or nil. "" and nil convert back to the
+// zero time, while the Unix time 0 converts back to the Unix epoch unless
+// WithUnixZeroTime is set. Converted pb values decode to times in UTC.
+type TimeFormat string
+
+const (
+	// TimeUnix, TimeUnixMilli, TimeUnixMicro and TimeUnixNano convert time
+	// fields to an int64 Unix time in seconds, milliseconds, microseconds or
+	// nanoseconds. Nanoseconds overflow outside of the years 1678 to 2262.
+	TimeUnix      TimeFormat = "unix"
+	TimeUnixMilli TimeFormat = "unix_milli"
+	TimeUnixMicro TimeFormat = "unix_micro"
+	TimeUnixNano  TimeFormat = "unix_nano"
+	// TimeRFC3339 converts time fields to an RFC 3339 string with fractional
+	// seconds, for pb fields annotated with
+	// entproto.Type(descriptorpb.FieldDescriptorProto_TYPE_STRING). Malformed
+	// strings are returned as a *ConversionError.
+	TimeRFC3339 TimeFormat = "rfc3339"
+	// TimeTimestamp converts time fields to a google.protobuf.Timestamp, for pb
+	// fields annotated with entproto.MessageField(n, &timestamppb.Timestamp{}).
+	TimeTimestamp TimeFormat = "timestamp"
+)
+
+// WithTimeFormat sets the pb representation of the time fields without a
+// WithFieldTimeFormat. By default it matches the pb field type: Unix seconds
+// for int64 fields, RFC 3339 for strings and google.protobuf.Timestamp.
+// entproto emits int64 time fields unless annotated otherwise, so TimeRFC3339
+// and TimeTimestamp require the matching annotation on every time field they
+// apply to; generation fails listing the fields without it.
+func WithTimeFormat(f TimeFormat) Option {
+	return func(o *Options) {
+		o.TimeFormat = f
+	}
+}
+
+// WithFieldTimeFormat sets the pb representation of a single time field of an
+// ent type, e.g. to keep the milliseconds of an audit timestamp:
+//
+//	entconv.WithFieldTimeFormat("User", "created_at", entconv.TimeUnixMilli)
+func WithFieldTimeFormat(typeName, fieldName string, f TimeFormat) Option {
+	return func(o *Options) {
+		if o.FieldTimeFormats == nil {
+			o.FieldTimeFormats = make(map[string]map[string]TimeFormat)
+		}
+		if o.FieldTimeFormats[typeName] == nil {
+			o.FieldTimeFormats[typeName] = make(map[string]TimeFormat)
+		}
+		o.FieldTimeFormats[typeName][fieldName] = f
+	}
+}
+
+// WithUnixZeroTime makes the converters decode the Unix time 0 to the zero
+// time, so that the zero time round-trips through the Unix formats. The Unix
+// epoch itself is lost then: 1970-01-01T00:00:00Z converts to 0 and back to the
+// zero time.
+func WithUnixZeroTime(v bool) Option {
+	return func(o *Options) {
+		o.UnixZeroTime = v
+	}
+}
+
+// resolveFieldTimeFormats checks the time formats of fields against the graph
+// and returns them keyed by generator.FieldKey.
+func resolveFieldTimeFormats(g *gen.Graph, formats map[string]map[string]TimeFormat) (map[string]converter.TimeFormat, error) {
+	out := make(map[string]converter.TimeFormat)
+	for typeName, fields := range formats {
+		for fieldName, f := range fields {
+			if err := checkEntField(g, typeName, fieldName); err != nil {
+				return nil, fmt.Errorf("time format: %w", err)
+			}
+			if !isTimeField(g, typeName, fieldName) {
+				return nil, fmt.Errorf("time format: field %s of ent type %s is not a time field", fieldName, typeName)
+			}
+			if !converter.TimeFormat(f).Valid() {
+				return nil, fmt.Errorf("time format of %s.%s: invalid TimeFormat %q", typeName, fieldName, f)
+			}
+			out[generator.FieldKey(typeName, fieldName)] = converter.TimeFormat(f)
+		}
+	}
+	return out, nil
+}
+
+// isTimeField reports whether the field of the ent type is a time field.
+func isTimeField(g *gen.Graph, typeName, fieldName string) bool {
+	for _, node := range g.Nodes {
+		if node.Name != typeName {
+			continue
+		}
+		for _, f := range node.Fields {
+			if f.Name == fieldName {
+				return f.IsTime()
+			}
+		}
+	}
+	return false
+}
+
+// checkTimeFormat checks the time format of cg against the pb field types of
+// the time fields without a format of their own or a field converter, and
+// reports all the mismatching fields at once.
+func checkTimeFormat(cg *generator.Generator) error {
+	if cg.TimeFormat == "" {
+		return nil
+	}
+	var mismatched []string
+	for _, ti := range cg.Types {
+		if ti.Subset {
+			continue
+		}
+		fm, err := cg.FieldMap(ti.Type.Name)
+		if err != nil {
+			return err
+		}
+		for _, f := range fm.Fields() {
+			ef, pbd := f.EntField, f.PbFieldDescriptor
+			if ef == nil || !ef.IsTime() {
+				continue
+			}
+			key := generator.FieldKey(ti.Type.Name, ef.Name)
+			if _, ok := cg.FieldTimeFormats[key]; ok {
+				continue
+			}
+			if _, ok := cg.FieldConverters[key]; ok {
+				continue
+			}
+			// Time fields without any matching format are unsupported, see CoverageReport.
+			if converter.MatchingTimeFormat(pbd) != "" && !cg.TimeFormat.Fits(pbd) {
+				mismatched = append(mismatched, fmt.Sprintf("%s.%s (%s)", ti.Type.Name, ef.Name, pbd.GetType()))
+			}
+		}
+	}
+	if len(mismatched) > 0 {
+		return fmt.Errorf("time format %q does not match the pb field type of %s: annotate their pb type or set their format with WithFieldTimeFormat", cg.TimeFormat, strings.Join(mismatched, ", "))
+	}
+	return nil
+}
@@ -26,18 +26,23 @@ var (
 )
 
 // LoadAdapter takes a *gen.Graph and parses it into protobuf file descriptors
-func LoadAdapter(graph *gen.Graph) (*Adapter, error) {
+func LoadAdapter(graph *gen.Graph, opts ...AdapterOption) (*Adapter, error) {
 	a := &Adapter{
 		graph:            graph,
 		nodeByName:       make(map[string]*gen.Type, len(graph.Nodes)),
+		nodeByMessage:    make(map[string]*gen.Type, len(graph.Nodes)),
 		protoPkgByType:   make(map[string]string, len(graph.Nodes)),
 		descriptors:      make(map[string]*desc.FileDescriptor),
 		schemaProtoFiles: make(map[string]string),
 		externalFiles:    make(map[string]struct{}),
 		errors:           make(map[string]error),
 	}
+	for _, opt := range opts {
+		opt(a)
+	}
 	for _, node := range graph.Nodes {
 		a.nodeByName[node.Name] = node
+		a.nodeByMessage[messageName(node)] = node
 	}
 	if err := a.parse(); err != nil {
 		return nil, err
@@ -47,8 +52,10 @@ func LoadAdapter(graph *gen.Graph) (*Adapter, error) {
 
 // Adapter facilitates the transformation of ent gen.Type to desc.FileDescriptors
 type Adapter struct {
-	graph            *gen.Graph
-	nodeByName       map[string]*gen.Type
+	graph      *gen.Graph
+	nodeByName map[string]*gen.Type
+	// nodeByMessage indexes the nodes by the name of their message, see MessageName.
+	nodeByMessage    map[string]*gen.Type
 	protoPkgByType   map[string]string
 	descriptors      map[string]*desc.FileDescriptor
 	schemaProtoFiles map[string]string
@@ -56,7 +63,12 @@ type Adapter struct {
 	// satisfy cross-file linking for RegisterCustomType references. They must
 	// not be written to disk.
 	externalFiles map[string]struct{}
-	errors        map[string]error
+	// registeredImports holds, per message, the proto files resolved from the
+	// global registry that must be imported, see addRegisteredImports.
+	registeredImports map[string][]string
+	errors            map[string]error
+	// deriveFieldBehavior enables google.api.field_behavior derivation, see DeriveFieldBehavior.
+	deriveFieldBehavior bool
 }
 
 // AllFileDescriptors returns a file descriptor per proto package for each package that contains
@@ -90,7 +102,11 @@ func (a *Adapter) GetMessageDescriptor(schemaName string) (*desc.MessageDescript
 	if err != nil {
 		return nil, err
 	}
-	findMessage := fd.FindMessage(fd.GetPackage() + "." + schemaName)
+	msgName := schemaName
+	if node, ok := a.nodeByName[schemaName]; ok {
+		msgName = messageName(node)
+	}
+	findMessage := fd.FindMessage(fd.GetPackage() + "." + msgName)
 	if findMessage != nil {
 		return findMessage, nil
 	}
@@ -104,6 +120,7 @@ func (a *Adapter) parse() error {
 	protoPackages := make(map[string]*descriptorpb.FileDescriptorProto)
 	protoPackageDeps := make(map[string]map[string]struct{})
 	customStubs := map[string]*descriptorpb.FileDescriptorProto{}
+	registeredFiles := map[string]*descriptorpb.FileDescriptorProto{}
 
 	for _, genType := range a.graph.Nodes {
 		messageDescriptor, err := a.toProtoMessageDescriptor(genType)
@@ -114,6 +131,12 @@ func (a *Adapter) parse() error {
 			continue
 		}
 
+		crudDescriptors, err := a.toCRUDMessageDescriptors(genType, messageDescriptor)
+		if err != nil {
+			a.errors[genType.Name] = err
+			continue
+		}
+
 		protoPkg, err := a.protoPackageName(genType)
 		if err != nil {
 			a.errors[genType.Name] = err
@@ -134,6 +157,7 @@ func (a *Adapter) parse() error {
 		}
 		fd := protoPackages[protoPkg]
 		fd.MessageType = append(fd.MessageType, messageDescriptor)
+		fd.MessageType = append(fd.MessageType, crudDescriptors...)
 		a.schemaProtoFiles[genType.Name] = *fd.Name
 
 		depPaths, err := a.extractDepPaths(protoPkg, messageDescriptor, customStubs)
@@ -141,6 +165,16 @@ func (a *Adapter) parse() error {
 			a.errors[genType.Name] = err
 			continue
 		}
+		for _, registeredImport := range a.registeredImports[genType.Name] {
+			if err := loadRegisteredFile(registeredImport, registeredFiles); err != nil {
+				a.errors[genType.Name] = err
+				break
+			}
+			depPaths = append(depPaths, registeredImport)
+		}
+		if _, failed := a.errors[genType.Name]; failed {
+			continue
+		}
 		for _, depPath := range depPaths {
 			depSet, ok := protoPackageDeps[protoPkg]
 			if !ok || depSet == nil {
@@ -159,9 +193,17 @@ func (a *Adapter) parse() error {
 		dpbDescriptors = append(dpbDescriptors, fd)
 	}
 	for _, stub := range customStubs {
+		// Prefer the real descriptor when the same file is also needed for options.
+		if _, ok := registeredFiles[stub.GetName()]; ok {
+			continue
+		}
 		dpbDescriptors = append(dpbDescriptors, stub)
 		a.externalFiles[stub.GetName()] = struct{}{}
 	}
+	for _, file := range registeredFiles {
+		dpbDescriptors = append(dpbDescriptors, file)
+		a.externalFiles[file.GetName()] = struct{}{}
+	}
 
 	descriptors, err := desc.CreateFileDescriptors(dpbDescriptors)
 	if err != nil {
@@ -260,8 +302,12 @@ func (a *Adapter) extractDepPaths(selfPackageName string, m *descriptorpb.Descri
 			out = append(out, entry.ProtoFile)
 			continue
 		}
+		if isOtherTypeMessage(fieldTypeName) {
+			// Imported through the registered imports of the message.
+			continue
+		}
 		depTypeName := protoTypeShortName(fieldTypeName)
-		depType, ok := a.nodeByName[depTypeName]
+		depType, ok := a.nodeByMessage[depTypeName]
 		if !ok {
 			return nil, fmt.Errorf("entproto: failed extracting deps, unknown path for %s", fieldTypeName)
 		}
@@ -288,20 +334,31 @@ func (a *Adapter) toProtoMessageDescriptor(genType *gen.Type) (*descriptorpb.Des
 		return nil, ErrSchemaSkipped
 	}
 	msg := &descriptorpb.DescriptorProto{
-		Name:     &genType.Name,
+		Name:     toPtr(messageName(genType)),
 		EnumType: []*descriptorpb.EnumDescriptorProto(nil),
 	}
+	msgOpts, msgImports, err := extractMessageOptions(genType)
+	if err != nil {
+		return nil, err
+	}
+	if msgOpts != nil {
+		msg.Options = msgOpts
+		a.addRegisteredImports(genType.Name, msgImports)
+	}
 
-	if !genType.ID.UserDefined {
-		if genType.ID.Annotations == nil {
-			genType.ID.Annotations = make(map[string]any, 1)
-		}
-		if _, exists := genType.ID.Annotations[FieldAnnotation]; !exists {
-			genType.ID.Annotations[FieldAnnotation] = Field(IDFieldNumber)
+	// Views have no ID field, their messages only carry the view fields.
+	var all []*gen.Field
+	if genType.ID != nil {
+		if !genType.ID.UserDefined {
+			if genType.ID.Annotations == nil {
+				genType.ID.Annotations = make(map[string]any, 1)
+			}
+			if _, exists := genType.ID.Annotations[FieldAnnotation]; !exists {
+				genType.ID.Annotations[FieldAnnotation] = Field(IDFieldNumber)
+			}
 		}
+		all = append(all, genType.ID)
 	}
-
-	all := []*gen.Field{genType.ID}
 	all = append(all, genType.Fields...)
 
 	for _, f := range all {
@@ -313,6 +370,16 @@ func (a *Adapter) toProtoMessageDescriptor(genType *gen.Type) (*descriptorpb.Des
 		if err != nil {
 			return nil, err
 		}
+		if ot, ok := LookupOtherType(f.Type); ok && ot.ProtoFile != "" &&
+			protoField.GetTypeName() == normalizeCustomTypeName(ot.PbTypeName) {
+			a.addRegisteredImports(genType.Name, []string{ot.ProtoFile})
+		}
+		if err := a.applyFieldOptions(genType.Name, protoField, f.Annotations, f.Name); err != nil {
+			return nil, err
+		}
+		if err := a.applyFieldBehavior(genType.Name, protoField, f, f == genType.ID); err != nil {
+			return nil, err
+		}
 		// If the field is an enum type, we need to create the enum descriptor as well.
 		if f.Type.Type == field.TypeEnum {
 			dp, err := toProtoEnumDescriptor(f)
@@ -338,6 +405,11 @@ func (a *Adapter) toProtoMessageDescriptor(genType *gen.Type) (*descriptorpb.Des
 		}
 	}
 
+	if err := applyOneOfs(genType, msg); err != nil {
+		return nil, err
+	}
+	addSyntheticOneOfs(msg)
+
 	// Verify no duplicate field numbers
 	seen := make(map[int32]struct{})
 	for _, fld := range msg.Field {
@@ -383,6 +455,9 @@ func (a *Adapter) extractEdgeFieldDescriptor(source *gen.Type, e *gen.Edge) (*de
 	if !e.Unique {
 		fieldDesc.Label = &repeatedFieldLabel
 	}
+	if err := a.applyFieldOptions(source.Name, fieldDesc, e.Annotations, e.Name); err != nil {
+		return nil, err
+	}
 
 	relType, ok := a.nodeByName[msgTypeName]
 	if !ok {
@@ -397,10 +472,11 @@ func (a *Adapter) extractEdgeFieldDescriptor(source *gen.Type, e *gen.Edge) (*de
 	if err != nil {
 		return nil, err
 	}
+	dstMsgName := messageName(relType)
 	if sourceAnnotation.Package == dstAnnotation.Package {
-		fieldDesc.TypeName = &msgTypeName
+		fieldDesc.TypeName = &dstMsgName
 	} else {
-		fqn := dstAnnotation.Package + "." + msgTypeName
+		fqn := dstAnnotation.Package + "." + dstMsgName
 		fieldDesc.TypeName = &fqn
 	}
 
@@ -439,7 +515,7 @@ func toProtoEnumDescriptor(fld *gen.Field) (*descriptorpb.EnumDescriptorProto, e
 	return dp, nil
 }
 
-func toProtoFieldDescriptor(f *gen.Field) (*descriptorpb.FieldDescriptorProto, error) {
+func toProtoFieldDescriptor(f *gen.Field) (_ *descriptorpb.FieldDescriptorProto, err error) {
 	fieldDesc := &descriptorpb.FieldDescriptorProto{
 		Name: &f.Name,
 	}
@@ -455,6 +531,21 @@ func toProtoFieldDescriptor(f *gen.Field) (*descriptorpb.FieldDescriptorProto, e
 		return nil, fmt.Errorf("entproto: field %q has number 1 which is reserved for id", f.Name)
 	}
 	fieldDesc.Number = &fieldNumber
+	defer func() {
+		if fann.Optional && err == nil {
+			err = setProto3Optional(f, fieldDesc)
+		}
+	}()
+	if fann.JSONType != descriptorpb.FieldDescriptorProto_Type(0) {
+		switch {
+		case f.Type.Type != field.TypeJSON:
+			return nil, fmt.Errorf("entproto: field %q of type %q cannot be JSON encoded, only JSON fields can", f.Name, f.Type.ConstName())
+		case fann.JSONType != descriptorpb.FieldDescriptorProto_TYPE_BYTES && fann.JSONType != descriptorpb.FieldDescriptorProto_TYPE_STRING:
+			return nil, fmt.Errorf("entproto: JSON field %q cannot be encoded as %q, only as bytes or string", f.Name, fann.JSONType)
+		}
+		fieldDesc.Type = &fann.JSONType
+		return fieldDesc, nil
+	}
 	if fann.Type != descriptorpb.FieldDescriptorProto_Type(0) {
 		fieldDesc.Type = &fann.Type
 		if len(fann.TypeName) > 0 {
@@ -468,6 +559,11 @@ func toProtoFieldDescriptor(f *gen.Field) (*descriptorpb.FieldDescriptorProto, e
 				registerCustomType(fann.TypeName, fann.ProtoFile)
 			}
 		}
+		// A JSON column holding a slice of messages is a repeated field.
+		if fann.Type == descriptorpb.FieldDescriptorProto_TYPE_MESSAGE && f.Type.Type == field.TypeJSON &&
+			strings.HasPrefix(f.Type.Ident, "[]") {
+			fieldDesc.Label = &repeatedFieldLabel
+		}
 		return fieldDesc, nil
 	}
 
@@ -496,9 +592,18 @@ func toProtoFieldDescriptor(f *gen.Field) (*descriptorpb.FieldDescriptorProto, e
 		default:
 			return nil, fmt.Errorf("unsupported field type %q", f.Type.ConstName())
 		}
+	} else if ot, ok := LookupOtherType(f.Type); ok {
+		pbType = ot.PbType
+		if ot.PbTypeName != "" {
+			msgName = normalizeCustomTypeName(ot.PbTypeName)
+		}
 	} else {
 		cfg, ok := typeMap[f.Type.Type]
 		if !ok || cfg.unsupported {
+			if f.Type.Type == field.TypeOther {
+				return nil, fmt.Errorf("unsupported field type %q: register %s with entproto.RegisterOtherType",
+					f.Type.ConstName(), f.Type.Ident)
+			}
 			return nil, fmt.Errorf("unsupported field type %q", f.Type.ConstName())
 		}
 		pbType = cfg.pbType
@@ -518,6 +623,30 @@ func toProtoFieldDescriptor(f *gen.Field) (*descriptorpb.FieldDescriptorProto, e
 	return fieldDesc, nil
 }
 
+// setProto3Optional gives fieldDesc explicit presence. The synthetic oneof of
+// the field is declared by addSyntheticOneOfs once the message is complete.
+func setProto3Optional(f *gen.Field, fieldDesc *descriptorpb.FieldDescriptorProto) error {
+	if fieldDesc.GetLabel() == descriptorpb.FieldDescriptorProto_LABEL_REPEATED ||
+		fieldDesc.GetType() == descriptorpb.FieldDescriptorProto_TYPE_MESSAGE {
+		return fmt.Errorf("entproto: field %q cannot be proto3 optional, only singular scalar and enum fields can", f.Name)
+	}
+	fieldDesc.Label = descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+	fieldDesc.Proto3Optional = toPtr(true)
+	return nil
+}
+
+// addSyntheticOneOfs declares the synthetic oneofs of the proto3 optional fields
+// of msg. They must follow all the real oneofs.
+func addSyntheticOneOfs(msg *descriptorpb.DescriptorProto) {
+	for _, fld := range msg.Field {
+		if !fld.GetProto3Optional() {
+			continue
+		}
+		fld.OneofIndex = toPtr(int32(len(msg.OneofDecl))) //nolint:gosec
+		msg.OneofDecl = append(msg.OneofDecl, &descriptorpb.OneofDescriptorProto{Name: toPtr("_" + fld.GetName())})
+	}
+}
+
 func toPtr[T any](t T) *T {
 	return &t
 }
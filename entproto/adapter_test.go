@@ -1,12 +1,20 @@
 package entproto
 
 import (
+	"errors"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 
 	"entgo.io/ent/entc"
 	"entgo.io/ent/entc/gen"
+	"entgo.io/ent/schema"
 	"entgo.io/ent/schema/field"
+	"github.com/go-sphere/entc-extensions/entproto/testdata/othertype"
+	"github.com/jhump/protoreflect/desc/protoprint" //nolint:staticcheck
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/descriptorpb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
@@ -126,6 +134,380 @@ func TestLoadAdapter_MessageField(t *testing.T) {
 	}
 }
 
+func TestLoadAdapter_FieldAndMessageOptions(t *testing.T) {
+	schemaPath := "./testdata/schema/options"
+	g, err := entc.LoadGraph(schemaPath, &gen.Config{
+		Target:  filepath.Join(t.TempDir(), "ent"),
+		IDType:  &field.TypeInfo{Type: field.TypeInt64},
+		Package: "github.com/go-sphere/entc-extensions/entproto/testdata/ent",
+	})
+	if err != nil {
+		t.Fatalf("LoadGraph failed: %v", err)
+	}
+
+	a, err := LoadAdapter(g)
+	if err != nil {
+		t.Fatalf("LoadAdapter failed: %v", err)
+	}
+	msg, err := a.GetMessageDescriptor("User")
+	if err != nil {
+		t.Fatalf("GetMessageDescriptor(User) failed: %v", err)
+	}
+	if !msg.GetMessageOptions().GetDeprecated() {
+		t.Fatalf("User message options not deprecated: %v", msg.GetMessageOptions())
+	}
+	if !msg.FindFieldByName("legacy_name").GetFieldOptions().GetDeprecated() {
+		t.Fatalf("legacy_name field options not deprecated")
+	}
+
+	etagOpts := msg.FindFieldByName("etag").GetFieldOptions()
+	behavior, ok := proto.GetExtension(etagOpts, annotations.E_FieldBehavior).([]annotations.FieldBehavior)
+	if !ok || len(behavior) != 1 || behavior[0] != annotations.FieldBehavior_OUTPUT_ONLY {
+		t.Fatalf("etag field_behavior=%v, want [OUTPUT_ONLY]", behavior)
+	}
+
+	const behaviorFile = "google/api/field_behavior.proto"
+	fd := msg.GetFile()
+	var imported bool
+	for _, dep := range fd.GetDependencies() {
+		imported = imported || dep.GetName() == behaviorFile
+	}
+	if !imported {
+		t.Fatalf("expected %s import, got %v", behaviorFile, fd.GetDependencies())
+	}
+	if _, ext := a.GeneratedFileDescriptors()[behaviorFile]; ext {
+		t.Fatalf("generated descriptors should not include %s", behaviorFile)
+	}
+
+	var printer protoprint.Printer
+	var out strings.Builder
+	if err := printer.PrintProtoFile(fd, &out); err != nil {
+		t.Fatalf("PrintProtoFile failed: %v", err)
+	}
+	for _, want := range []string{
+		`import "google/api/field_behavior.proto";`,
+		"option deprecated = true;",
+		"string legacy_name = 3 [deprecated = true];",
+		"string etag = 4 [(google.api.field_behavior) = OUTPUT_ONLY];",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("printed proto missing %q; output:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestLoadAdapter_DeriveFieldBehavior(t *testing.T) {
+	schemaPath := "./testdata/schema/behavior"
+	g, err := entc.LoadGraph(schemaPath, &gen.Config{
+		Target:  filepath.Join(t.TempDir(), "ent"),
+		IDType:  &field.TypeInfo{Type: field.TypeInt64},
+		Package: "github.com/go-sphere/entc-extensions/entproto/testdata/ent",
+	})
+	if err != nil {
+		t.Fatalf("LoadGraph failed: %v", err)
+	}
+
+	a, err := LoadAdapter(g, DeriveFieldBehavior())
+	if err != nil {
+		t.Fatalf("LoadAdapter failed: %v", err)
+	}
+	msg, err := a.GetMessageDescriptor("Account")
+	if err != nil {
+		t.Fatalf("GetMessageDescriptor(Account) failed: %v", err)
+	}
+
+	const (
+		required   = annotations.FieldBehavior_REQUIRED
+		optional   = annotations.FieldBehavior_OPTIONAL
+		immutable  = annotations.FieldBehavior_IMMUTABLE
+		outputOnly = annotations.FieldBehavior_OUTPUT_ONLY
+	)
+	tests := map[string][]annotations.FieldBehavior{
+		"id":           {outputOnly},
+		"email":        {required},
+		"nickname":     {optional},
+		"region":       {required, immutable},
+		"created_at":   {outputOnly},
+		"updated_at":   {outputOnly},
+		"active":       {optional},
+		"display_name": {required},
+		"notes":        nil,
+	}
+	for name, want := range tests {
+		fld := msg.FindFieldByName(name)
+		if fld == nil {
+			t.Fatalf("field %q not found", name)
+		}
+		got, _ := proto.GetExtension(fld.GetFieldOptions(), annotations.E_FieldBehavior).([]annotations.FieldBehavior)
+		if len(got) != len(want) {
+			t.Fatalf("%s field_behavior=%v, want %v", name, got, want)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("%s field_behavior=%v, want %v", name, got, want)
+			}
+		}
+	}
+
+	var imported bool
+	for _, dep := range msg.GetFile().GetDependencies() {
+		imported = imported || dep.GetName() == fieldBehaviorProtoFile
+	}
+	if !imported {
+		t.Fatalf("expected %s import, got %v", fieldBehaviorProtoFile, msg.GetFile().GetDependencies())
+	}
+
+	plain, err := LoadAdapter(g)
+	if err != nil {
+		t.Fatalf("LoadAdapter without options failed: %v", err)
+	}
+	plainMsg, err := plain.GetMessageDescriptor("Account")
+	if err != nil {
+		t.Fatalf("GetMessageDescriptor(Account) failed: %v", err)
+	}
+	if opts := plainMsg.FindFieldByName("email").GetFieldOptions(); opts != nil && proto.HasExtension(opts, annotations.E_FieldBehavior) {
+		t.Fatalf("field_behavior derived without DeriveFieldBehavior")
+	}
+}
+
+func TestLoadAdapter_CRUDMessages(t *testing.T) {
+	schemaPath := "./testdata/schema/crud"
+	g, err := entc.LoadGraph(schemaPath, &gen.Config{
+		Target:  filepath.Join(t.TempDir(), "ent"),
+		IDType:  &field.TypeInfo{Type: field.TypeInt64},
+		Package: "github.com/go-sphere/entc-extensions/entproto/testdata/ent",
+	})
+	if err != nil {
+		t.Fatalf("LoadGraph failed: %v", err)
+	}
+
+	a, err := LoadAdapter(g)
+	if err != nil {
+		t.Fatalf("LoadAdapter failed: %v", err)
+	}
+	fd, err := a.GetFileDescriptor("Task")
+	if err != nil {
+		t.Fatalf("GetFileDescriptor(Task) failed: %v", err)
+	}
+
+	fieldNames := func(name string) []string {
+		t.Helper()
+		msg := fd.FindMessage(fd.GetPackage() + "." + name)
+		if msg == nil {
+			t.Fatalf("message %s not generated", name)
+		}
+		var out []string
+		for _, fld := range msg.GetFields() {
+			out = append(out, fld.GetName())
+		}
+		return out
+	}
+	tests := map[string][]string{
+		"CreateTaskRequest": {"title", "status", "labels"},
+		"GetTaskRequest":    {"id"},
+		"UpdateTaskRequest": {"task", "update_mask"},
+		"DeleteTaskRequest": {"id"},
+		"ListTasksRequest":  {"page_size", "page_token", "order_by", "order_desc"},
+		"ListTasksResponse": {"tasks", "next_page_token"},
+	}
+	for name, want := range tests {
+		if got := fieldNames(name); strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Fatalf("%s fields=%v, want %v", name, got, want)
+		}
+	}
+
+	create := fd.FindMessage(fd.GetPackage() + ".CreateTaskRequest")
+	if got := create.FindFieldByName("status").GetEnumType().GetFullyQualifiedName(); got != "entpb.Task.Status" {
+		t.Fatalf("CreateTaskRequest.status enum=%q, want entpb.Task.Status", got)
+	}
+	update := fd.FindMessage(fd.GetPackage() + ".UpdateTaskRequest")
+	if got := update.FindFieldByName("update_mask").GetMessageType().GetFullyQualifiedName(); got != "google.protobuf.FieldMask" {
+		t.Fatalf("UpdateTaskRequest.update_mask type=%q, want google.protobuf.FieldMask", got)
+	}
+
+	list := fd.FindMessage(fd.GetPackage() + ".ListTasksRequest")
+	orderField := list.FindFieldByName("order_by").GetEnumType()
+	if orderField == nil {
+		t.Fatalf("ListTasksRequest.OrderField enum not generated")
+	}
+	var orderValues []string
+	for _, v := range orderField.GetValues() {
+		orderValues = append(orderValues, v.GetName())
+	}
+	wantOrder := "ORDER_FIELD_UNSPECIFIED,ORDER_FIELD_ID,ORDER_FIELD_TITLE,ORDER_FIELD_STATUS,ORDER_FIELD_CREATED_AT,ORDER_FIELD_UPDATED_AT"
+	if got := strings.Join(orderValues, ","); got != wantOrder {
+		t.Fatalf("OrderField values=%s, want %s", got, wantOrder)
+	}
+	if got := orderField.FindValueByName("ORDER_FIELD_CREATED_AT").GetNumber(); got != 5 {
+		t.Fatalf("ORDER_FIELD_CREATED_AT=%d, want the field number 5", got)
+	}
+
+	if _, ext := a.GeneratedFileDescriptors()[fieldMaskProtoFile]; ext {
+		t.Fatalf("generated descriptors should not include %s", fieldMaskProtoFile)
+	}
+}
+
+func TestLoadAdapter_MessageName(t *testing.T) {
+	schemaPath := "./testdata/schema/msgname"
+	g, err := entc.LoadGraph(schemaPath, &gen.Config{
+		Target:  filepath.Join(t.TempDir(), "ent"),
+		IDType:  &field.TypeInfo{Type: field.TypeInt64},
+		Package: "github.com/go-sphere/entc-extensions/entproto/testdata/ent",
+	})
+	if err != nil {
+		t.Fatalf("LoadGraph failed: %v", err)
+	}
+
+	a, err := LoadAdapter(g)
+	if err != nil {
+		t.Fatalf("LoadAdapter failed: %v", err)
+	}
+	md, err := a.GetMessageDescriptor("Account")
+	if err != nil {
+		t.Fatalf("GetMessageDescriptor(Account) failed: %v", err)
+	}
+	if got := md.GetFullyQualifiedName(); got != "entpb.AccountEntity" {
+		t.Fatalf("Account message=%q, want entpb.AccountEntity", got)
+	}
+	fd := md.GetFile()
+	if got := fd.FindMessage("entpb.CreateAccountRequest").FindFieldByName("status").GetEnumType().GetFullyQualifiedName(); got != "entpb.AccountEntity.Status" {
+		t.Fatalf("CreateAccountRequest.status enum=%q, want entpb.AccountEntity.Status", got)
+	}
+	if got := fd.FindMessage("entpb.UpdateAccountRequest").FindFieldByName("account").GetMessageType(); got != md {
+		t.Fatalf("UpdateAccountRequest.account type=%v, want %v", got, md)
+	}
+
+	owner, err := a.GetMessageDescriptor("Owner")
+	if err != nil {
+		t.Fatalf("GetMessageDescriptor(Owner) failed: %v", err)
+	}
+	if got := owner.FindFieldByName("accounts").GetMessageType().GetFullyQualifiedName(); got != "entpb.AccountEntity" {
+		t.Fatalf("Owner.accounts type=%q, want entpb.AccountEntity", got)
+	}
+	fm, err := a.FieldMap("Account")
+	if err != nil {
+		t.Fatalf("FieldMap(Account) failed: %v", err)
+	}
+	if _, ok := fm["email"]; !ok {
+		t.Fatalf("FieldMap(Account) misses email: %v", fm)
+	}
+}
+
+func TestExtension_AdapterIsShared(t *testing.T) {
+	g, err := entc.LoadGraph("./testdata/schema/msgname", &gen.Config{
+		Target:  filepath.Join(t.TempDir(), "ent"),
+		IDType:  &field.TypeInfo{Type: field.TypeInt64},
+		Package: "github.com/go-sphere/entc-extensions/entproto/testdata/ent",
+	})
+	if err != nil {
+		t.Fatalf("LoadGraph failed: %v", err)
+	}
+	ex, err := NewExtension(WithProtoDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("NewExtension failed: %v", err)
+	}
+	a, err := ex.Adapter(g)
+	if err != nil {
+		t.Fatalf("Adapter failed: %v", err)
+	}
+	if err := ex.generate(g); err != nil {
+		t.Fatalf("generate failed: %v", err)
+	}
+	if again, _ := ex.Adapter(g); again != a {
+		t.Fatal("Adapter should return the adapter loaded for the graph")
+	}
+}
+
+func TestLoadAdapter_OneOf(t *testing.T) {
+	schemaPath := "./testdata/schema/oneof"
+	g, err := entc.LoadGraph(schemaPath, &gen.Config{
+		Target:  filepath.Join(t.TempDir(), "ent"),
+		IDType:  &field.TypeInfo{Type: field.TypeInt64},
+		Package: "github.com/go-sphere/entc-extensions/entproto/testdata/ent",
+	})
+	if err != nil {
+		t.Fatalf("LoadGraph failed: %v", err)
+	}
+
+	a, err := LoadAdapter(g)
+	if err != nil {
+		t.Fatalf("LoadAdapter failed: %v", err)
+	}
+	fd, err := a.GetFileDescriptor("Payment")
+	if err != nil {
+		t.Fatalf("GetFileDescriptor(Payment) failed: %v", err)
+	}
+
+	for _, name := range []string{"Payment", "CreatePaymentRequest"} {
+		msg := fd.FindMessage(fd.GetPackage() + "." + name)
+		oneOfs := msg.GetOneOfs()
+		if len(oneOfs) != 2 || oneOfs[0].GetName() != "payment_method" || oneOfs[1].GetName() != "_note" {
+			t.Fatalf("%s oneofs=%v, want [payment_method _note]", name, oneOfs)
+		}
+		if note := msg.FindFieldByName("note"); !note.IsProto3Optional() || note.GetOneOf() != oneOfs[1] {
+			t.Fatalf("%s.note should be proto3 optional", name)
+		}
+		var members []string
+		for _, fld := range oneOfs[0].GetChoices() {
+			members = append(members, fld.GetName())
+		}
+		if got := strings.Join(members, ","); got != "card_token,bank_account_id,wallet_id" {
+			t.Fatalf("%s payment_method fields=%s", name, got)
+		}
+		if msg.FindFieldByName("amount").GetOneOf() != nil {
+			t.Fatalf("%s.amount should not be part of a oneof", name)
+		}
+	}
+
+	fm, err := a.FieldMap("Payment")
+	if err != nil {
+		t.Fatalf("FieldMap failed: %v", err)
+	}
+	oneOfs := fm.OneOfs()
+	if len(oneOfs) != 1 || oneOfs[0].PbFieldName() != "PaymentMethod" || len(oneOfs[0].Fields) != 3 {
+		t.Fatalf("FieldMap.OneOfs()=%v, want the payment_method group", oneOfs)
+	}
+	if got := fm["card_token"].PbOneOfWrapper(); got != "Payment_CardToken" {
+		t.Fatalf("PbOneOfWrapper=%q, want Payment_CardToken", got)
+	}
+}
+
+func TestApplyOneOfs_Validation(t *testing.T) {
+	tests := map[string][]schema.Annotation{
+		"unknown field":   {OneOf("choice", "a", "missing")},
+		"id field":        {OneOf("choice", "id", "a")},
+		"repeated field":  {OneOf("choice", "a", "tags")},
+		"field in two":    {OneOf("first", "a", "b"), OneOf("second", "b")},
+		"duplicate name":  {OneOf("choice", "a"), OneOf("choice", "b")},
+		"empty group":     {OneOf("choice")},
+		"field conflicts": {OneOf("a", "b")},
+		"optional field":  {OneOf("choice", "a", "c")},
+	}
+	for name, annots := range tests {
+		t.Run(name, func(t *testing.T) {
+			annot := annots[0]
+			for _, other := range annots[1:] {
+				annot = annot.(schema.Merger).Merge(other)
+			}
+			node := &gen.Type{
+				Name: "Demo",
+				ID:   &gen.Field{Name: "id", Type: &field.TypeInfo{Type: field.TypeInt}},
+				Fields: []*gen.Field{
+					{Name: "a", Type: &field.TypeInfo{Type: field.TypeString}, Annotations: map[string]any{FieldAnnotation: Field(2)}},
+					{Name: "b", Type: &field.TypeInfo{Type: field.TypeString}, Annotations: map[string]any{FieldAnnotation: Field(3)}},
+					{Name: "tags", Type: &field.TypeInfo{Type: field.TypeJSON, Ident: "[]string"}, Annotations: map[string]any{FieldAnnotation: Field(4)}},
+					{Name: "c", Type: &field.TypeInfo{Type: field.TypeString}, Annotations: map[string]any{FieldAnnotation: Field(5, Optional())}},
+				},
+				Annotations: map[string]any{MessageAnnotation: Message(), OneOfAnnotation: annot},
+			}
+			_, err := (&Adapter{}).toProtoMessageDescriptor(node)
+			if !errors.Is(err, ErrInvalidAnnotation) {
+				t.Fatalf("toProtoMessageDescriptor error=%v, want ErrInvalidAnnotation", err)
+			}
+		})
+	}
+}
+
 func TestRegisterCustomType_FromMessage(t *testing.T) {
 	resetCustomTypeRegistry()
 	t.Cleanup(resetCustomTypeRegistry)
@@ -160,3 +542,175 @@ func TestRegisterCustomType_NilPanics(t *testing.T) {
 	}()
 	RegisterCustomType(nil)
 }
+
+func TestLoadAdapter_ViewsAndOtherTypes(t *testing.T) {
+	resetOtherTypeRegistry()
+	t.Cleanup(resetOtherTypeRegistry)
+	RegisterOtherType(othertype.MoneyToProto, othertype.MoneyFromProto)
+	RegisterOtherType(othertype.InstantToProto, othertype.InstantFromProto)
+
+	schemaPath := "./testdata/schema/view"
+	g, err := entc.LoadGraph(schemaPath, &gen.Config{
+		Target:  filepath.Join(t.TempDir(), "ent"),
+		IDType:  &field.TypeInfo{Type: field.TypeInt64},
+		Package: "github.com/go-sphere/entc-extensions/entproto/testdata/ent",
+	})
+	if err != nil {
+		t.Fatalf("LoadGraph failed: %v", err)
+	}
+
+	a, err := LoadAdapter(g)
+	if err != nil {
+		t.Fatalf("LoadAdapter failed: %v", err)
+	}
+	view, err := a.GetMessageDescriptor("OrderTotals")
+	if err != nil {
+		t.Fatalf("GetMessageDescriptor(OrderTotals) failed: %v", err)
+	}
+	var viewFields []string
+	for _, fld := range view.GetFields() {
+		viewFields = append(viewFields, fld.GetName())
+	}
+	if got := strings.Join(viewFields, ","); got != "customer,orders" {
+		t.Fatalf("OrderTotals fields=%s, want customer,orders", got)
+	}
+	fm, err := a.FieldMap("OrderTotals")
+	if err != nil {
+		t.Fatalf("FieldMap(OrderTotals) failed: %v", err)
+	}
+	if fm.ID() != nil {
+		t.Fatalf("view field map should have no id")
+	}
+
+	order, err := a.GetMessageDescriptor("Order")
+	if err != nil {
+		t.Fatalf("GetMessageDescriptor(Order) failed: %v", err)
+	}
+	if got := order.FindFieldByName("total").GetType(); got != descriptorpb.FieldDescriptorProto_TYPE_INT64 {
+		t.Fatalf("Order.total type=%v, want TYPE_INT64", got)
+	}
+	if got := order.FindFieldByName("placed_at").GetMessageType().GetFullyQualifiedName(); got != "google.protobuf.Timestamp" {
+		t.Fatalf("Order.placed_at type=%q, want google.protobuf.Timestamp", got)
+	}
+	fd, err := a.GetFileDescriptor("Order")
+	if err != nil {
+		t.Fatalf("GetFileDescriptor(Order) failed: %v", err)
+	}
+	var deps []string
+	for _, dep := range fd.GetDependencies() {
+		deps = append(deps, dep.GetName())
+	}
+	if !slices.Contains(deps, "google/protobuf/timestamp.proto") {
+		t.Fatalf("dependencies=%v, want google/protobuf/timestamp.proto", deps)
+	}
+}
+
+func TestRegisterOtherType(t *testing.T) {
+	resetOtherTypeRegistry()
+	t.Cleanup(resetOtherTypeRegistry)
+	RegisterOtherType(othertype.InstantToProto, othertype.InstantFromProto)
+
+	ot, ok := LookupOtherType(&field.TypeInfo{
+		Type:    field.TypeOther,
+		Ident:   "othertype.Instant",
+		PkgPath: "github.com/go-sphere/entc-extensions/entproto/testdata/othertype",
+	})
+	if !ok {
+		t.Fatalf("expected othertype.Instant to be registered")
+	}
+	want := GoFunc{PkgPath: "github.com/go-sphere/entc-extensions/entproto/testdata/othertype", Name: "InstantFromProto"}
+	if ot.ToEnt != want || !ot.ToEntReturnsError {
+		t.Fatalf("ToEnt=%+v (error=%v), want %+v returning an error", ot.ToEnt, ot.ToEntReturnsError, want)
+	}
+
+	for name, fns := range map[string][2]any{
+		"mismatched types": {othertype.MoneyToProto, othertype.InstantFromProto},
+		"closure":          {func(othertype.Money) int64 { return 0 }, othertype.MoneyFromProto},
+		"not a func":       {othertype.Money{}, othertype.MoneyFromProto},
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Fatalf("expected panic")
+				}
+			}()
+			RegisterOtherType(fns[0], fns[1])
+		})
+	}
+}
+
+func TestToProtoFieldDescriptor_Optional(t *testing.T) {
+	fld, err := toProtoFieldDescriptor(&gen.Field{
+		Name:        "nickname",
+		Type:        &field.TypeInfo{Type: field.TypeString},
+		Annotations: map[string]any{FieldAnnotation: Field(3, Optional())},
+	})
+	if err != nil {
+		t.Fatalf("toProtoFieldDescriptor failed: %v", err)
+	}
+	if !fld.GetProto3Optional() || fld.GetLabel() != descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL {
+		t.Fatalf("nickname=%v, want a proto3 optional field", fld)
+	}
+
+	_, err = toProtoFieldDescriptor(&gen.Field{
+		Name:        "tags",
+		Type:        &field.TypeInfo{Type: field.TypeJSON, Ident: "[]string"},
+		Annotations: map[string]any{FieldAnnotation: Field(4, Optional())},
+	})
+	if err == nil {
+		t.Fatal("expected an error for a proto3 optional repeated field")
+	}
+}
+
+func TestToProtoFieldDescriptor_EncodeJSON(t *testing.T) {
+	for _, typ := range []descriptorpb.FieldDescriptorProto_Type{
+		descriptorpb.FieldDescriptorProto_TYPE_BYTES,
+		descriptorpb.FieldDescriptorProto_TYPE_STRING,
+	} {
+		fld, err := toProtoFieldDescriptor(&gen.Field{
+			Name:        "settings",
+			Type:        &field.TypeInfo{Type: field.TypeJSON, Ident: "schema.Settings"},
+			Annotations: map[string]any{FieldAnnotation: Field(5, EncodeJSON(typ))},
+		})
+		if err != nil {
+			t.Fatalf("toProtoFieldDescriptor failed: %v", err)
+		}
+		if fld.GetType() != typ || fld.GetLabel() == descriptorpb.FieldDescriptorProto_LABEL_REPEATED {
+			t.Fatalf("settings=%v, want a singular %v field", fld, typ)
+		}
+	}
+
+	_, err := toProtoFieldDescriptor(&gen.Field{
+		Name:        "settings",
+		Type:        &field.TypeInfo{Type: field.TypeJSON, Ident: "schema.Settings"},
+		Annotations: map[string]any{FieldAnnotation: Field(5, EncodeJSON(descriptorpb.FieldDescriptorProto_TYPE_INT64))},
+	})
+	if err == nil {
+		t.Fatal("expected an error for a JSON field encoded as int64")
+	}
+	_, err = toProtoFieldDescriptor(&gen.Field{
+		Name:        "name",
+		Type:        &field.TypeInfo{Type: field.TypeString},
+		Annotations: map[string]any{FieldAnnotation: Field(2, EncodeJSON(descriptorpb.FieldDescriptorProto_TYPE_BYTES))},
+	})
+	if err == nil {
+		t.Fatal("expected an error for a JSON encoded string field")
+	}
+}
+
+func TestToProtoFieldDescriptor_RepeatedMessageField(t *testing.T) {
+	resetCustomTypeRegistry()
+	t.Cleanup(resetCustomTypeRegistry)
+
+	fld, err := toProtoFieldDescriptor(&gen.Field{
+		Name:        "checkpoints",
+		Type:        &field.TypeInfo{Type: field.TypeJSON, Ident: "[]*timestamppb.Timestamp"},
+		Annotations: map[string]any{FieldAnnotation: MessageField(4, &timestamppb.Timestamp{})},
+	})
+	if err != nil {
+		t.Fatalf("toProtoFieldDescriptor failed: %v", err)
+	}
+	if fld.GetLabel() != descriptorpb.FieldDescriptorProto_LABEL_REPEATED || fld.GetTypeName() != ".google.protobuf.Timestamp" {
+		t.Fatalf("checkpoints=%v, want a repeated google.protobuf.Timestamp field", fld)
+	}
+}
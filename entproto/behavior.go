@@ -0,0 +1,121 @@
+package entproto
+
+import (
+	"fmt"
+
+	"entgo.io/ent/entc/gen"
+	"entgo.io/ent/schema"
+	"github.com/go-viper/mapstructure/v2"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const FieldBehaviorAnnotation = "ProtoFieldBehavior"
+
+// fieldBehaviorProtoFile is the proto file declaring the google.api.field_behavior
+// extension. Like custom types, it is imported but never written by entproto.
+var fieldBehaviorProtoFile = annotations.File_google_api_field_behavior_proto.Path()
+
+// AdapterOption configures an Adapter created by LoadAdapter.
+type AdapterOption func(*Adapter)
+
+// DeriveFieldBehavior makes the adapter annotate generated fields with
+// google.api.field_behavior values derived from the ent field definition:
+//
+//   - OUTPUT_ONLY for server-managed fields: an ID that is not user defined,
+//     fields with an UpdateDefault, and immutable fields with a Default.
+//   - IMMUTABLE for other immutable fields (and user-defined IDs).
+//   - OPTIONAL for Optional fields and fields with a Default.
+//   - REQUIRED for all other fields.
+//
+// IMMUTABLE is combined with OPTIONAL or REQUIRED. Edges are left untouched.
+// Use the FieldBehavior annotation to override the derived values of a field.
+func DeriveFieldBehavior() AdapterOption {
+	return func(a *Adapter) {
+		a.deriveFieldBehavior = true
+	}
+}
+
+// FieldBehavior annotates an ent field with the google.api.field_behavior values
+// to emit when field behaviors are derived, replacing the derived ones. Calling it
+// without arguments disables field_behavior for the field.
+//
+//	field.String("name").
+//		Optional().
+//		Annotations(
+//			entproto.Field(2),
+//			entproto.FieldBehavior(annotations.FieldBehavior_REQUIRED),
+//		)
+func FieldBehavior(behaviors ...annotations.FieldBehavior) schema.Annotation {
+	return fieldBehavior{Behaviors: behaviors}
+}
+
+type fieldBehavior struct {
+	Behaviors []annotations.FieldBehavior
+}
+
+func (fieldBehavior) Name() string {
+	return FieldBehaviorAnnotation
+}
+
+func extractFieldBehaviorAnnotation(fld *gen.Field) (*fieldBehavior, error) {
+	annot, ok := fld.Annotations[FieldBehaviorAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	var out fieldBehavior
+	if err := mapstructure.Decode(annot, &out); err != nil {
+		return nil, fmt.Errorf("entproto: unable to decode entproto.FieldBehavior annotation for field %q: %w",
+			fld.Name, err)
+	}
+	return &out, nil
+}
+
+// deriveFieldBehaviors maps the ent field metadata to google.api.field_behavior values.
+func deriveFieldBehaviors(fld *gen.Field, isID bool) []annotations.FieldBehavior {
+	switch {
+	case isID && (!fld.UserDefined || fld.Default):
+		return []annotations.FieldBehavior{annotations.FieldBehavior_OUTPUT_ONLY}
+	case isServerManaged(fld):
+		return []annotations.FieldBehavior{annotations.FieldBehavior_OUTPUT_ONLY}
+	}
+	var out []annotations.FieldBehavior
+	if fld.Optional || fld.Default {
+		out = append(out, annotations.FieldBehavior_OPTIONAL)
+	} else {
+		out = append(out, annotations.FieldBehavior_REQUIRED)
+	}
+	if isID || fld.Immutable {
+		out = append(out, annotations.FieldBehavior_IMMUTABLE)
+	}
+	return out
+}
+
+// applyFieldBehavior sets google.api.field_behavior on fd when derivation is
+// enabled. Behaviors set explicitly through FieldOptions take precedence.
+func (a *Adapter) applyFieldBehavior(msgName string, fd *descriptorpb.FieldDescriptorProto, fld *gen.Field, isID bool) error {
+	if !a.deriveFieldBehavior {
+		return nil
+	}
+	if fd.Options != nil && proto.HasExtension(fd.Options, annotations.E_FieldBehavior) {
+		return nil
+	}
+	behaviors := deriveFieldBehaviors(fld, isID)
+	override, err := extractFieldBehaviorAnnotation(fld)
+	if err != nil {
+		return err
+	}
+	if override != nil {
+		behaviors = override.Behaviors
+	}
+	if len(behaviors) == 0 {
+		return nil
+	}
+	if fd.Options == nil {
+		fd.Options = &descriptorpb.FieldOptions{}
+	}
+	proto.SetExtension(fd.Options, annotations.E_FieldBehavior, behaviors)
+	a.addRegisteredImports(msgName, []string{fieldBehaviorProtoFile})
+	return nil
+}
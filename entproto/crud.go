@@ -0,0 +1,240 @@
+package entproto
+
+import (
+	"fmt"
+	"strings"
+
+	"entgo.io/ent/entc/gen"
+	"entgo.io/ent/schema/field"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+// Method is a bitmask selecting the request/response messages generated by WithCRUDMessages.
+type Method uint
+
+const (
+	// MethodCreate generates Create<T>Request carrying the client-settable fields.
+	MethodCreate Method = 1 << iota
+	// MethodGet generates Get<T>Request carrying the id.
+	MethodGet
+	// MethodUpdate generates Update<T>Request carrying the message and an update mask.
+	MethodUpdate
+	// MethodDelete generates Delete<T>Request carrying the id.
+	MethodDelete
+	// MethodList generates List<Ts>Request and List<Ts>Response with paging and ordering.
+	MethodList
+	// MethodAll generates all the messages above.
+	MethodAll = MethodCreate | MethodGet | MethodUpdate | MethodDelete | MethodList
+)
+
+var (
+	fieldMaskProtoFile = fieldmaskpb.File_google_protobuf_field_mask_proto.Path()
+	fieldMaskTypeName  = "." + string((&fieldmaskpb.FieldMask{}).ProtoReflect().Descriptor().FullName())
+)
+
+// WithCRUDMessages generates request/response messages for the schema next to its
+// message, without any RPC service. Without arguments, messages for all methods
+// are generated:
+//
+//	entproto.Message(entproto.WithCRUDMessages(entproto.MethodCreate | entproto.MethodList))
+//
+// The messages are derived from the schema: the create request omits the ID
+// (unless it is user defined without a default) and server-managed fields, the
+// update request carries a google.protobuf.FieldMask, and the list request has
+// page_size/page_token paging and an OrderField enum with a value per sortable
+// field, numbered after the field.
+func WithCRUDMessages(methods ...Method) MessageOption {
+	return func(msg *message) {
+		msg.CRUD = 0
+		for _, m := range methods {
+			msg.CRUD |= m
+		}
+		if msg.CRUD == 0 {
+			msg.CRUD = MethodAll
+		}
+	}
+}
+
+// isServerManaged reports whether the value of fld is owned by the server: it is
+// either reset on every update or defaulted once and never changed.
+func isServerManaged(fld *gen.Field) bool {
+	return fld.UpdateDefault || fld.Immutable && fld.Default
+}
+
+func (a *Adapter) toCRUDMessageDescriptors(genType *gen.Type, msg *descriptorpb.DescriptorProto) ([]*descriptorpb.DescriptorProto, error) {
+	msgAnnot, err := extractMessageAnnotation(genType)
+	if err != nil {
+		return nil, err
+	}
+	methods := msgAnnot.CRUD
+	if methods == 0 {
+		return nil, nil
+	}
+
+	pbFields := make(map[string]*descriptorpb.FieldDescriptorProto, len(msg.Field))
+	for _, fld := range msg.Field {
+		pbFields[fld.GetName()] = fld
+	}
+	if genType.ID == nil && methods&^MethodList != 0 {
+		return nil, fmt.Errorf("entproto: view %q has no id field, only list messages can be generated", genType.Name)
+	}
+	var idField *descriptorpb.FieldDescriptorProto
+	if genType.ID != nil {
+		idField = pbFields[genType.ID.Name]
+	}
+	if idField == nil && methods&(MethodGet|MethodDelete) != 0 {
+		return nil, fmt.Errorf("entproto: schema %q skips its id field, cannot generate get/delete requests", genType.Name)
+	}
+
+	var out []*descriptorpb.DescriptorProto
+	if methods&MethodCreate != 0 {
+		out = append(out, createRequestDescriptor(genType, msg, pbFields))
+	}
+	if methods&MethodGet != 0 {
+		out = append(out, idRequestDescriptor("Get"+genType.Name+"Request", idField))
+	}
+	if methods&MethodUpdate != 0 {
+		out = append(out, &descriptorpb.DescriptorProto{
+			Name: toPtr("Update" + genType.Name + "Request"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				messageFieldDescriptor(snake(genType.Name), 1, messageName(genType), false),
+				messageFieldDescriptor("update_mask", 2, fieldMaskTypeName, false),
+			},
+		})
+		a.addRegisteredImports(genType.Name, []string{fieldMaskProtoFile})
+	}
+	if methods&MethodDelete != 0 {
+		out = append(out, idRequestDescriptor("Delete"+genType.Name+"Request", idField))
+	}
+	if methods&MethodList != 0 {
+		out = append(out, listRequestDescriptor(genType, pbFields), listResponseDescriptor(genType))
+	}
+
+	for _, m := range out {
+		if _, exists := a.nodeByName[m.GetName()]; exists {
+			return nil, fmt.Errorf("entproto: generated message %q for schema %q conflicts with schema of the same name",
+				m.GetName(), genType.Name)
+		}
+	}
+	return out, nil
+}
+
+func createRequestDescriptor(genType *gen.Type, msg *descriptorpb.DescriptorProto, pbFields map[string]*descriptorpb.FieldDescriptorProto) *descriptorpb.DescriptorProto {
+	m := &descriptorpb.DescriptorProto{Name: toPtr("Create" + genType.Name + "Request")}
+	if id := genType.ID; id.UserDefined && !id.Default {
+		if fld, ok := pbFields[id.Name]; ok {
+			m.Field = append(m.Field, scopedFieldCopy(messageName(genType), fld))
+		}
+	}
+	for _, f := range genType.Fields {
+		fld, ok := pbFields[f.Name]
+		if !ok || isServerManaged(f) {
+			continue
+		}
+		m.Field = append(m.Field, scopedFieldCopy(messageName(genType), fld))
+	}
+	copyOneOfs(msg, m)
+	return m
+}
+
+func idRequestDescriptor(name string, idField *descriptorpb.FieldDescriptorProto) *descriptorpb.DescriptorProto {
+	id := proto.CloneOf(idField)
+	id.Name = toPtr("id")
+	id.Number = toPtr[int32](IDFieldNumber)
+	id.Options = nil
+	return &descriptorpb.DescriptorProto{
+		Name:  toPtr(name),
+		Field: []*descriptorpb.FieldDescriptorProto{id},
+	}
+}
+
+func listRequestDescriptor(genType *gen.Type, pbFields map[string]*descriptorpb.FieldDescriptorProto) *descriptorpb.DescriptorProto {
+	orderField := &descriptorpb.EnumDescriptorProto{
+		Name: toPtr("OrderField"),
+		Value: []*descriptorpb.EnumValueDescriptorProto{
+			{Name: toPtr("ORDER_FIELD_UNSPECIFIED"), Number: toPtr[int32](0)},
+		},
+	}
+	fields := genType.Fields
+	if genType.ID != nil {
+		fields = append([]*gen.Field{genType.ID}, fields...)
+	}
+	for _, f := range fields {
+		fld, ok := pbFields[f.Name]
+		if !ok || !isSortable(f) {
+			continue
+		}
+		orderField.Value = append(orderField.Value, &descriptorpb.EnumValueDescriptorProto{
+			Name:   toPtr("ORDER_FIELD_" + strings.ToUpper(snake(f.Name))),
+			Number: toPtr(fld.GetNumber()),
+		})
+	}
+	return &descriptorpb.DescriptorProto{
+		Name: toPtr("List" + plural(genType.Name) + "Request"),
+		Field: []*descriptorpb.FieldDescriptorProto{
+			scalarFieldDescriptor("page_size", 1, descriptorpb.FieldDescriptorProto_TYPE_INT32),
+			scalarFieldDescriptor("page_token", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+			{
+				Name:     toPtr("order_by"),
+				Number:   toPtr[int32](3),
+				Type:     descriptorpb.FieldDescriptorProto_TYPE_ENUM.Enum(),
+				TypeName: orderField.Name,
+			},
+			scalarFieldDescriptor("order_desc", 4, descriptorpb.FieldDescriptorProto_TYPE_BOOL),
+		},
+		EnumType: []*descriptorpb.EnumDescriptorProto{orderField},
+	}
+}
+
+func listResponseDescriptor(genType *gen.Type) *descriptorpb.DescriptorProto {
+	return &descriptorpb.DescriptorProto{
+		Name: toPtr("List" + plural(genType.Name) + "Response"),
+		Field: []*descriptorpb.FieldDescriptorProto{
+			messageFieldDescriptor(snake(plural(genType.Name)), 1, messageName(genType), true),
+			scalarFieldDescriptor("next_page_token", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+		},
+	}
+}
+
+// isSortable reports whether rows can be ordered by fld.
+func isSortable(fld *gen.Field) bool {
+	switch fld.Type.Type {
+	case field.TypeJSON, field.TypeBytes, field.TypeOther:
+		return false
+	}
+	return true
+}
+
+// scopedFieldCopy copies a field of the schema message into another message.
+// Enums are nested in the schema message, so relative enum type names are
+// qualified with it.
+func scopedFieldCopy(msgName string, fld *descriptorpb.FieldDescriptorProto) *descriptorpb.FieldDescriptorProto {
+	out := proto.CloneOf(fld)
+	if out.GetType() == descriptorpb.FieldDescriptorProto_TYPE_ENUM && !strings.HasPrefix(out.GetTypeName(), ".") {
+		out.TypeName = toPtr(msgName + "." + out.GetTypeName())
+	}
+	return out
+}
+
+func scalarFieldDescriptor(name string, num int32, typ descriptorpb.FieldDescriptorProto_Type) *descriptorpb.FieldDescriptorProto {
+	return &descriptorpb.FieldDescriptorProto{
+		Name:   toPtr(name),
+		Number: toPtr(num),
+		Type:   typ.Enum(),
+	}
+}
+
+func messageFieldDescriptor(name string, num int32, typeName string, repeated bool) *descriptorpb.FieldDescriptorProto {
+	fld := &descriptorpb.FieldDescriptorProto{
+		Name:     toPtr(name),
+		Number:   toPtr(num),
+		Type:     descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum(),
+		TypeName: toPtr(typeName),
+	}
+	if repeated {
+		fld.Label = &repeatedFieldLabel
+	}
+	return fld
+}
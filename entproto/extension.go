@@ -39,8 +39,12 @@ func NewExtension(opts ...ExtensionOption) (*Extension, error) {
 //	}
 type Extension struct {
 	entc.DefaultExtension
-	protoDir string
-	autoFill bool
+	protoDir      string
+	autoFill      bool
+	fieldBehavior bool
+	// graph and adapter cache the adapter loaded by Adapter.
+	graph   *gen.Graph
+	adapter *Adapter
 }
 
 // WithProtoDir sets the directory where the generated .proto files will be written.
@@ -59,6 +63,15 @@ func WithAutoFill() ExtensionOption {
 	}
 }
 
+// WithFieldBehavior enables deriving google.api.field_behavior annotations from the
+// ent field definitions (Immutable, Default, UpdateDefault, Optional). See
+// DeriveFieldBehavior for the mapping and FieldBehavior for per-field overrides.
+func WithFieldBehavior() ExtensionOption {
+	return func(e *Extension) {
+		e.fieldBehavior = true
+	}
+}
+
 // Hooks implements entc.Extension.
 func (e *Extension) Hooks() []gen.Hook {
 	return []gen.Hook{e.hook()}
@@ -74,11 +87,6 @@ func (e *Extension) hook() gen.Hook {
 			if err != nil {
 				return err
 			}
-			if e.autoFill {
-				if err := FixGraph(g); err != nil {
-					return err
-				}
-			}
 			return e.generate(g)
 		})
 	}
@@ -105,14 +113,38 @@ func Generate(g *gen.Graph) error {
 	return x.generate(g)
 }
 
+// Adapter returns the adapter of the extension for g, loading it on first use.
+// Other extensions of the same codegen pass, such as entconv, share it whether
+// they run before or after this one. With WithAutoFill, g is fixed first.
+func (e *Extension) Adapter(g *gen.Graph) (*Adapter, error) {
+	if e.adapter != nil && e.graph == g {
+		return e.adapter, nil
+	}
+	if e.autoFill {
+		if err := FixGraph(g); err != nil {
+			return nil, err
+		}
+	}
+	var adapterOpts []AdapterOption
+	if e.fieldBehavior {
+		adapterOpts = append(adapterOpts, DeriveFieldBehavior())
+	}
+	adapter, err := LoadAdapter(g, adapterOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("entproto: failed parsing ent graph: %w", err)
+	}
+	e.graph, e.adapter = g, adapter
+	return adapter, nil
+}
+
 func (e *Extension) generate(g *gen.Graph) error {
 	entProtoDir := path.Join(g.Target, "proto")
 	if e.protoDir != "" {
 		entProtoDir = e.protoDir
 	}
-	adapter, err := LoadAdapter(g)
+	adapter, err := e.Adapter(g)
 	if err != nil {
-		return fmt.Errorf("entproto: failed parsing ent graph: %w", err)
+		return err
 	}
 	var errs error
 	for _, schema := range g.Schemas {
@@ -32,6 +32,11 @@ type pbfield struct {
 	// RegisterCustomType call. It serialises along with the rest of the
 	// annotation via mapstructure.
 	ProtoFile string
+	// Optional marks the field as proto3 optional, giving it explicit presence.
+	Optional bool
+	// JSONType is the bytes or string type of the pb field holding the JSON
+	// encoding of a JSON field, set with EncodeJSON.
+	JSONType descriptorpb.FieldDescriptorProto_Type
 }
 
 func (f pbfield) Name() string {
@@ -60,6 +65,32 @@ func TypeName(n string) FieldOption {
 	}
 }
 
+// Optional generates the field with the proto3 optional label, so that an unset
+// value can be told apart from the zero value. entconv maps it to nil for
+// Nillable ent fields. It cannot be used on message or repeated fields.
+//
+//	field.String("nickname").
+//		Optional().
+//		Nillable().
+//		Annotations(entproto.Field(3, entproto.Optional()))
+func Optional() FieldOption {
+	return func(p *pbfield) {
+		p.Optional = true
+	}
+}
+
+// EncodeJSON generates a JSON field holding an arbitrary Go value, which has no
+// pb counterpart, as a field of type typ, TYPE_BYTES or TYPE_STRING, holding
+// its JSON encoding. entconv encodes and decodes the value with encoding/json.
+//
+//	field.JSON("settings", Settings{}).
+//		Annotations(entproto.Field(5, entproto.EncodeJSON(descriptorpb.FieldDescriptorProto_TYPE_BYTES)))
+func EncodeJSON(typ descriptorpb.FieldDescriptorProto_Type) FieldOption {
+	return func(p *pbfield) {
+		p.JSONType = typ
+	}
+}
+
 // MessageField annotates an ent field that should be emitted as a protobuf
 // message reference to an externally-defined type. It reads the fully-qualified
 // type name and proto file path straight off the supplied generated Go message
@@ -73,7 +104,8 @@ func TypeName(n string) FieldOption {
 //		Annotations(entproto.MessageField(3, &sharedv1.User{}))
 //
 // The generator will emit `import "shared/v1/user.proto";` and reference the
-// field as `shared.v1.User user = 3;`.
+// field as `shared.v1.User user = 3;`. A column holding a slice of messages,
+// e.g. field.JSON("users", []*sharedv1.User{}), is a repeated field.
 func MessageField(num int, msg proto.Message) schema.Annotation {
 	if msg == nil {
 		panic("entproto: MessageField called with nil message")
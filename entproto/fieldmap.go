@@ -80,6 +80,55 @@ func (m FieldMap) Enums() []*FieldMappingDescriptor {
 	return out
 }
 
+// OneOfs returns the OneOfMappingDescriptor for all of the oneof groups of the schema, in declaration order.
+func (m FieldMap) OneOfs() []*OneOfMappingDescriptor {
+	byName := make(map[string]*OneOfMappingDescriptor)
+	var out []*OneOfMappingDescriptor
+	for _, f := range m.Fields() {
+		oneOf := f.PbFieldDescriptor.GetOneOf()
+		if !f.IsOneOfField || oneOf == nil {
+			continue
+		}
+		od, ok := byName[oneOf.GetName()]
+		if !ok {
+			od = &OneOfMappingDescriptor{PbOneOfDescriptor: oneOf}
+			byName[oneOf.GetName()] = od
+			out = append(out, od)
+		}
+		od.Fields = append(od.Fields, f)
+	}
+	for _, od := range out {
+		sort.Slice(od.Fields, func(i, j int) bool {
+			return od.Fields[i].PbFieldDescriptor.GetNumber() < od.Fields[j].PbFieldDescriptor.GetNumber()
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return oneOfIndex(out[i].PbOneOfDescriptor) < oneOfIndex(out[j].PbOneOfDescriptor)
+	})
+	return out
+}
+
+// OneOfMappingDescriptor describes a protobuf oneof and the ent fields grouped in it.
+type OneOfMappingDescriptor struct {
+	PbOneOfDescriptor *desc.OneOfDescriptor
+	// Fields are sorted by pb field number.
+	Fields []*FieldMappingDescriptor
+}
+
+// PbFieldName returns the PascalCase name of the Go interface field holding the oneof.
+func (d *OneOfMappingDescriptor) PbFieldName() string {
+	return toPascalCase(d.PbOneOfDescriptor.GetName())
+}
+
+func oneOfIndex(od *desc.OneOfDescriptor) int {
+	for i, o := range od.GetOwner().GetOneOfs() {
+		if o == od {
+			return i
+		}
+	}
+	return -1
+}
+
 // FieldMappingDescriptor describes the mapping from a protobuf field descriptor to an ent Schema field
 type FieldMappingDescriptor struct {
 	EntField          *gen.Field
@@ -88,9 +137,20 @@ type FieldMappingDescriptor struct {
 	IsEdgeField       bool
 	IsIDField         bool
 	IsEnumField       bool
+	IsOneOfField      bool
 	ReferencedPbType  *desc.MessageDescriptor
 }
 
+// EncodesJSON reports whether the pb field holds the JSON encoding of the ent
+// field, annotated with EncodeJSON.
+func (d *FieldMappingDescriptor) EncodesJSON() bool {
+	if d.EntField == nil {
+		return false
+	}
+	fann, err := extractFieldAnnotation(d.EntField)
+	return err == nil && fann.JSONType != 0
+}
+
 // PbStructField returns the camelCase name of the protobuf field.
 func (d *FieldMappingDescriptor) PbStructField() string {
 	return camelCase(d.PbFieldDescriptor.GetName())
@@ -116,6 +176,12 @@ func toPascalCase(s string) string {
 	return strings.Join(parts, "")
 }
 
+// PbOneOfWrapper returns the name of the generated Go type wrapping the field in its oneof,
+// e.g. Payment_CardToken.
+func (d *FieldMappingDescriptor) PbOneOfWrapper() string {
+	return toPascalCase(d.PbFieldDescriptor.GetOwner().GetName()) + "_" + d.PbFieldName()
+}
+
 // EdgeIDPbStructField returns the name for the id field  of the
 // entity this edge refers to.
 func (d *FieldMappingDescriptor) EdgeIDPbStructField() string {
@@ -131,7 +197,9 @@ func (d *FieldMappingDescriptor) EdgeIDPbStructFieldDesc() *desc.FieldDescriptor
 
 func (a *Adapter) mapFields(entType *gen.Type, pbType *desc.MessageDescriptor) (FieldMap, error) {
 	fieldByName := make(map[string]*gen.Field, len(entType.Fields)+1)
-	fieldByName[entType.ID.Name] = entType.ID
+	if entType.ID != nil {
+		fieldByName[entType.ID.Name] = entType.ID
+	}
 	for _, fld := range entType.Fields {
 		fieldByName[fld.Name] = fld
 	}
@@ -144,8 +212,9 @@ func (a *Adapter) mapFields(entType *gen.Type, pbType *desc.MessageDescriptor) (
 	for _, fld := range pbType.GetFields() {
 		fd := &FieldMappingDescriptor{
 			PbFieldDescriptor: fld,
-			IsIDField:         pascal(fld.GetName()) == pascal(entType.ID.Name),
+			IsIDField:         entType.ID != nil && pascal(fld.GetName()) == pascal(entType.ID.Name),
 			IsEnumField:       fld.GetEnumType() != nil,
+			IsOneOfField:      fld.GetOneOf() != nil && !fld.IsProto3Optional(),
 		}
 		edg, isEdge := edgeByName[fld.GetName()]
 		if isEdge {
@@ -42,9 +42,13 @@ func fixNode(node *gen.Type) error {
 		return node.Fields[i].Position.Index < node.Fields[j].Position.Index
 	})
 
-	// Add annotation for ID field
-	if err := addAnnotationForField(node.ID, idGenerator); err != nil {
-		return err
+	// Add annotation for ID field. Views have no ID, but number 1 stays reserved for it.
+	if node.ID != nil {
+		if err := addAnnotationForField(node.ID, idGenerator); err != nil {
+			return err
+		}
+	} else {
+		idGenerator.exist[IDFieldNumber] = struct{}{}
 	}
 
 	// Add annotation for other fields
@@ -5,4 +5,5 @@ import "entgo.io/ent/entc/gen"
 var (
 	snake  = gen.Funcs["snake"].(func(string) string)
 	pascal = gen.Funcs["pascal"].(func(string) string)
+	plural = gen.Funcs["plural"].(func(string) string)
 )
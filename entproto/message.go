@@ -41,9 +41,21 @@ func PackageName(pkg string) MessageOption {
 	}
 }
 
+// MessageName sets the name of the generated message, which defaults to the
+// schema name. The CRUD request and response messages keep the schema name.
+func MessageName(name string) MessageOption {
+	return func(msg *message) {
+		msg.MessageName = name
+	}
+}
+
 type message struct {
 	Generate bool
 	Package  string
+	// MessageName overrides the name of the message, see MessageName.
+	MessageName string
+	// CRUD selects the request/response messages generated next to the message.
+	CRUD Method
 }
 
 func (m message) Name() string {
@@ -69,3 +81,11 @@ func extractMessageAnnotation(sch *gen.Type) (*message, error) {
 
 	return &out, nil
 }
+
+// messageName returns the name of the message generated for the schema.
+func messageName(sch *gen.Type) string {
+	if m, err := extractMessageAnnotation(sch); err == nil && m.MessageName != "" {
+		return m.MessageName
+	}
+	return sch.Name
+}
@@ -0,0 +1,158 @@
+package entproto
+
+import (
+	"fmt"
+
+	"entgo.io/ent/entc/gen"
+	"entgo.io/ent/schema"
+	"github.com/go-viper/mapstructure/v2"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const OneOfAnnotation = "ProtoOneOf"
+
+// OneOf annotates an ent.Schema to group mutually exclusive fields into a
+// protobuf oneof. The annotation may be repeated to declare several groups:
+//
+//	func (Payment) Annotations() []schema.Annotation {
+//		return []schema.Annotation{
+//			entproto.Message(),
+//			entproto.OneOf("payment_method", "card_token", "bank_account_id", "wallet_id"),
+//		}
+//	}
+//
+// A field may belong to at most one group, and neither the ID nor repeated
+// fields can be grouped. The fields keep the numbers set by entproto.Field.
+func OneOf(name string, fields ...string) schema.Annotation {
+	return oneOfs{Groups: []oneOfGroup{{Name: name, Fields: fields}}}
+}
+
+type oneOfGroup struct {
+	Name   string
+	Fields []string
+}
+
+type oneOfs struct {
+	Groups []oneOfGroup
+}
+
+func (oneOfs) Name() string {
+	return OneOfAnnotation
+}
+
+// Merge accumulates the groups of repeated OneOf annotations.
+func (o oneOfs) Merge(other schema.Annotation) schema.Annotation {
+	var groups []oneOfGroup
+	switch other := other.(type) {
+	case oneOfs:
+		groups = other.Groups
+	case *oneOfs:
+		if other != nil {
+			groups = other.Groups
+		}
+	default:
+		return o
+	}
+	return oneOfs{Groups: append(append([]oneOfGroup(nil), o.Groups...), groups...)}
+}
+
+func extractOneOfAnnotation(sch *gen.Type) (*oneOfs, error) {
+	annot, ok := sch.Annotations[OneOfAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	var out oneOfs
+	if err := mapstructure.Decode(annot, &out); err != nil {
+		return nil, fmt.Errorf("entproto: unable to decode entproto.OneOf annotation for schema %q: %w",
+			sch.Name, err)
+	}
+	return &out, nil
+}
+
+// applyOneOfs declares the oneof groups of genType on msg and assigns the
+// grouped fields to them.
+func applyOneOfs(genType *gen.Type, msg *descriptorpb.DescriptorProto) error {
+	annot, err := extractOneOfAnnotation(genType)
+	if err != nil || annot == nil {
+		return err
+	}
+	invalid := func(format string, args ...any) error {
+		return &InvalidAnnotationError{
+			Schema:     genType.Name,
+			Annotation: OneOfAnnotation,
+			Cause:      fmt.Errorf(format, args...),
+		}
+	}
+
+	entFields := make(map[string]bool, len(genType.Fields))
+	for _, f := range genType.Fields {
+		entFields[f.Name] = true
+	}
+	pbFields := make(map[string]*descriptorpb.FieldDescriptorProto, len(msg.Field))
+	for _, fld := range msg.Field {
+		pbFields[fld.GetName()] = fld
+	}
+
+	groupOf := make(map[string]string)
+	for i, g := range annot.Groups {
+		if g.Name == "" {
+			return invalid("oneof name must not be empty")
+		}
+		if len(g.Fields) == 0 {
+			return invalid("oneof %q has no fields", g.Name)
+		}
+		if _, exists := pbFields[g.Name]; exists {
+			return invalid("oneof %q conflicts with a field of the same name", g.Name)
+		}
+		for _, prev := range annot.Groups[:i] {
+			if prev.Name == g.Name {
+				return invalid("oneof %q is declared more than once", g.Name)
+			}
+		}
+		for _, name := range g.Fields {
+			if prev, exists := groupOf[name]; exists {
+				return invalid("field %q is in oneof %q and %q", name, prev, g.Name)
+			}
+			groupOf[name] = g.Name
+			switch fld, ok := pbFields[name]; {
+			case genType.ID != nil && name == genType.ID.Name:
+				return invalid("oneof %q cannot contain the id field", g.Name)
+			case !entFields[name]:
+				return invalid("oneof %q references unknown field %q", g.Name, name)
+			case !ok:
+				return invalid("oneof %q references skipped field %q", g.Name, name)
+			case fld.GetLabel() == descriptorpb.FieldDescriptorProto_LABEL_REPEATED:
+				return invalid("oneof %q cannot contain repeated field %q", g.Name, name)
+			case fld.GetProto3Optional():
+				return invalid("oneof %q cannot contain proto3 optional field %q", g.Name, name)
+			}
+			pbFields[name].OneofIndex = toPtr(int32(len(msg.OneofDecl))) //nolint:gosec
+		}
+		msg.OneofDecl = append(msg.OneofDecl, &descriptorpb.OneofDescriptorProto{Name: toPtr(g.Name)})
+	}
+	return nil
+}
+
+// copyOneOfs declares on dst the oneofs of src its fields belong to, and
+// renumbers their oneof indexes accordingly. Fields of dst are expected to be
+// copies of fields of src. Synthetic oneofs of proto3 optional fields are
+// declared after the real ones.
+func copyOneOfs(src, dst *descriptorpb.DescriptorProto) {
+	index := make(map[int32]int32)
+	for _, synthetic := range []bool{false, true} {
+		for _, fld := range dst.Field {
+			if fld.OneofIndex == nil || fld.GetProto3Optional() != synthetic {
+				continue
+			}
+			idx, ok := index[fld.GetOneofIndex()]
+			if !ok {
+				idx = int32(len(dst.OneofDecl)) //nolint:gosec
+				index[fld.GetOneofIndex()] = idx
+				dst.OneofDecl = append(dst.OneofDecl, &descriptorpb.OneofDescriptorProto{
+					Name: toPtr(src.OneofDecl[fld.GetOneofIndex()].GetName()),
+				})
+			}
+			fld.OneofIndex = toPtr(idx)
+		}
+	}
+}
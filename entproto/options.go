@@ -0,0 +1,148 @@
+package entproto
+
+import (
+	"encoding/base64"
+	"fmt"
+	"slices"
+
+	"entgo.io/ent/entc/gen"
+	"entgo.io/ent/schema"
+	"github.com/go-viper/mapstructure/v2"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const (
+	FieldOptionsAnnotation   = "ProtoFieldOptions"
+	MessageOptionsAnnotation = "ProtoMessageOptions"
+)
+
+// FieldOptions annotates an ent field or edge with protobuf field options that
+// are attached to the generated field descriptor. Extensions set on opts via
+// proto.SetExtension are kept, and the .proto files declaring them are imported
+// by the generated file.
+//
+//	opts := &descriptorpb.FieldOptions{Deprecated: proto.Bool(true)}
+//	proto.SetExtension(opts, annotations.E_FieldBehavior,
+//		[]annotations.FieldBehavior{annotations.FieldBehavior_OUTPUT_ONLY})
+//
+//	field.String("legacy_name").
+//		Annotations(entproto.Field(4), entproto.FieldOptions(opts))
+//
+// The Go package declaring an extension must also be linked into the program
+// running the generator so its descriptor can be found in the global registry.
+func FieldOptions(opts *descriptorpb.FieldOptions) schema.Annotation {
+	enc, imports := encodeOptions(opts)
+	return fieldOptions{Options: enc, Imports: imports}
+}
+
+// MessageOptions annotates an ent.Schema with protobuf message options that are
+// attached to the generated message descriptor. See FieldOptions for how
+// extensions are handled.
+func MessageOptions(opts *descriptorpb.MessageOptions) schema.Annotation {
+	enc, imports := encodeOptions(opts)
+	return messageOptions{Options: enc, Imports: imports}
+}
+
+// fieldOptions and messageOptions carry the options in their wire encoding so
+// that extensions survive the JSON round-trip ent performs on annotations, even
+// when the generator does not know them by Go type.
+type fieldOptions struct {
+	Options string
+	Imports []string
+}
+
+func (fieldOptions) Name() string {
+	return FieldOptionsAnnotation
+}
+
+type messageOptions struct {
+	Options string
+	Imports []string
+}
+
+func (messageOptions) Name() string {
+	return MessageOptionsAnnotation
+}
+
+func encodeOptions(opts proto.Message) (string, []string) {
+	if opts == nil {
+		panic("entproto: options annotation called with nil options")
+	}
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(opts)
+	if err != nil {
+		panic(fmt.Sprintf("entproto: marshalling %T: %v", opts, err))
+	}
+	var imports []string
+	proto.RangeExtensions(opts, func(xt protoreflect.ExtensionType, _ any) bool {
+		if file := xt.TypeDescriptor().ParentFile(); file != nil && !slices.Contains(imports, file.Path()) {
+			imports = append(imports, file.Path())
+		}
+		return true
+	})
+	slices.Sort(imports)
+	return base64.StdEncoding.EncodeToString(b), imports
+}
+
+func decodeOptions(enc string, into proto.Message) error {
+	b, err := base64.StdEncoding.DecodeString(enc)
+	if err != nil {
+		return err
+	}
+	return proto.Unmarshal(b, into)
+}
+
+// extractFieldOptions returns the options set on annotations through FieldOptions,
+// or nil if there are none.
+func extractFieldOptions(annotations map[string]any, name string) (*descriptorpb.FieldOptions, []string, error) {
+	annot, ok := annotations[FieldOptionsAnnotation]
+	if !ok {
+		return nil, nil, nil
+	}
+	var out fieldOptions
+	if err := mapstructure.Decode(annot, &out); err != nil {
+		return nil, nil, fmt.Errorf("entproto: unable to decode entproto.FieldOptions annotation for field %q: %w",
+			name, err)
+	}
+	opts := &descriptorpb.FieldOptions{}
+	if err := decodeOptions(out.Options, opts); err != nil {
+		return nil, nil, fmt.Errorf("entproto: unable to decode entproto.FieldOptions annotation for field %q: %w",
+			name, err)
+	}
+	return opts, out.Imports, nil
+}
+
+func extractMessageOptions(sch *gen.Type) (*descriptorpb.MessageOptions, []string, error) {
+	annot, ok := sch.Annotations[MessageOptionsAnnotation]
+	if !ok {
+		return nil, nil, nil
+	}
+	var out messageOptions
+	if err := mapstructure.Decode(annot, &out); err != nil {
+		return nil, nil, fmt.Errorf("entproto: unable to decode entproto.MessageOptions annotation for schema %q: %w",
+			sch.Name, err)
+	}
+	opts := &descriptorpb.MessageOptions{}
+	if err := decodeOptions(out.Options, opts); err != nil {
+		return nil, nil, fmt.Errorf("entproto: unable to decode entproto.MessageOptions annotation for schema %q: %w",
+			sch.Name, err)
+	}
+	return opts, out.Imports, nil
+}
+
+// applyFieldOptions merges the FieldOptions annotation found in annotations, if
+// any, into the options of fd.
+func (a *Adapter) applyFieldOptions(msgName string, fd *descriptorpb.FieldDescriptorProto, annotations map[string]any, name string) error {
+	opts, imports, err := extractFieldOptions(annotations, name)
+	if err != nil || opts == nil {
+		return err
+	}
+	if fd.Options == nil {
+		fd.Options = opts
+	} else {
+		proto.Merge(fd.Options, opts)
+	}
+	a.addRegisteredImports(msgName, imports)
+	return nil
+}
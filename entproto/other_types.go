@@ -0,0 +1,202 @@
+package entproto
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+
+	"entgo.io/ent/schema/field"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// OtherType describes how the Go type of field.Other columns is represented in
+// protobuf, as registered with RegisterOtherType.
+type OtherType struct {
+	// GoType is the Go type of the ent field, e.g. "decimal.Decimal".
+	GoType string
+	// PkgPath is the import path of the package declaring GoType.
+	PkgPath string
+	// PbType is the protobuf type of the generated field.
+	PbType descriptorpb.FieldDescriptorProto_Type
+	// PbTypeName is the fully-qualified name of the message, when PbType is TYPE_MESSAGE.
+	PbTypeName string
+	// ProtoFile is the .proto file declaring PbTypeName, imported by the generated file.
+	ProtoFile string
+	// ToProto converts the Go type to its protobuf Go value.
+	ToProto GoFunc
+	// ToEnt converts the protobuf Go value back to the Go type.
+	ToEnt GoFunc
+	// ToEntReturnsError reports whether ToEnt returns an error as its second result.
+	ToEntReturnsError bool
+}
+
+// GoFunc identifies a package-level Go function.
+type GoFunc struct {
+	PkgPath string
+	Name    string
+}
+
+var (
+	otherTypeRegistryMu sync.RWMutex
+	otherTypeRegistry   = map[string]*OtherType{}
+)
+
+// pbScalarKinds maps the Go kinds of protobuf scalar values to their protobuf type.
+var pbScalarKinds = map[reflect.Kind]descriptorpb.FieldDescriptorProto_Type{
+	reflect.Bool:    descriptorpb.FieldDescriptorProto_TYPE_BOOL,
+	reflect.String:  descriptorpb.FieldDescriptorProto_TYPE_STRING,
+	reflect.Int32:   descriptorpb.FieldDescriptorProto_TYPE_INT32,
+	reflect.Int64:   descriptorpb.FieldDescriptorProto_TYPE_INT64,
+	reflect.Uint32:  descriptorpb.FieldDescriptorProto_TYPE_UINT32,
+	reflect.Uint64:  descriptorpb.FieldDescriptorProto_TYPE_UINT64,
+	reflect.Float32: descriptorpb.FieldDescriptorProto_TYPE_FLOAT,
+	reflect.Float64: descriptorpb.FieldDescriptorProto_TYPE_DOUBLE,
+}
+
+// RegisterOtherType maps the Go type of field.Other columns to a protobuf type
+// through a pair of user-supplied conversion functions. The Go type and the
+// protobuf type are read from the function signatures:
+//
+//	func DecimalToProto(d decimal.Decimal) string
+//	func DecimalFromProto(s string) (decimal.Decimal, error)
+//
+//	entproto.RegisterOtherType(DecimalToProto, DecimalFromProto)
+//
+// The protobuf side is either a scalar (bool, string, []byte, 32/64-bit
+// integers and floats) or a generated message pointer, whose .proto file is
+// imported by the generated file. toEnt may return an error as its second
+// result. Both functions must be declared at package level, as entconv calls
+// them from the generated converters.
+//
+// Registration is process-wide, so it must happen both in the program running
+// entproto and in the one running entconv. Re-registering the same Go type
+// with different functions panics.
+func RegisterOtherType(toProto, toEnt any) {
+	ot, goType, err := newOtherType(toProto, toEnt)
+	if err != nil {
+		panic(fmt.Sprintf("entproto: RegisterOtherType: %v", err))
+	}
+	key := otherTypeKey(ot.PkgPath, goType.String())
+	otherTypeRegistryMu.Lock()
+	defer otherTypeRegistryMu.Unlock()
+	if existing, ok := otherTypeRegistry[key]; ok {
+		if *existing == *ot {
+			return
+		}
+		panic(fmt.Sprintf("entproto: other type %s already registered with %s.%s", goType, existing.ToProto.PkgPath, existing.ToProto.Name))
+	}
+	otherTypeRegistry[key] = ot
+}
+
+func newOtherType(toProto, toEnt any) (*OtherType, reflect.Type, error) {
+	tp, te := reflect.TypeOf(toProto), reflect.TypeOf(toEnt)
+	if tp == nil || tp.Kind() != reflect.Func || tp.NumIn() != 1 || tp.NumOut() != 1 {
+		return nil, nil, fmt.Errorf("toProto must be a func(T) P, got %T", toProto)
+	}
+	if te == nil || te.Kind() != reflect.Func || te.NumIn() != 1 || te.NumOut() < 1 || te.NumOut() > 2 ||
+		te.NumOut() == 2 && te.Out(1) != reflect.TypeFor[error]() {
+		return nil, nil, fmt.Errorf("toEnt must be a func(P) T or func(P) (T, error), got %T", toEnt)
+	}
+	goType, pbGoType := tp.In(0), tp.Out(0)
+	if te.In(0) != pbGoType || te.Out(0) != goType {
+		return nil, nil, fmt.Errorf("toProto (%T) and toEnt (%T) do not convert between the same types", toProto, toEnt)
+	}
+	ot := &OtherType{
+		GoType:            goType.String(),
+		PkgPath:           indirect(goType).PkgPath(),
+		ToEntReturnsError: te.NumOut() == 2,
+	}
+	var err error
+	if ot.ToProto, err = GoFuncOf(toProto); err != nil {
+		return nil, nil, err
+	}
+	if ot.ToEnt, err = GoFuncOf(toEnt); err != nil {
+		return nil, nil, err
+	}
+	switch {
+	case pbGoType == reflect.TypeFor[[]byte]():
+		ot.PbType = descriptorpb.FieldDescriptorProto_TYPE_BYTES
+	case pbGoType.Implements(reflect.TypeFor[proto.Message]()) && pbGoType.Kind() == reflect.Ptr:
+		d := reflect.New(pbGoType.Elem()).Interface().(proto.Message).ProtoReflect().Descriptor()
+		ot.PbType = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE
+		ot.PbTypeName = string(d.FullName())
+		ot.ProtoFile = d.ParentFile().Path()
+	default:
+		typ, ok := pbScalarKinds[pbGoType.Kind()]
+		if !ok || pbGoType.PkgPath() != "" {
+			return nil, nil, fmt.Errorf("%s is neither a protobuf scalar type nor a generated message", pbGoType)
+		}
+		ot.PbType = typ
+	}
+	return ot, goType, nil
+}
+
+// GoFuncOf returns the package path and name of the package-level function fn.
+// Closures and methods are rejected, as generated code cannot reference them.
+func GoFuncOf(fn any) (GoFunc, error) {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return GoFunc{}, fmt.Errorf("%T is not a function", fn)
+	}
+	full := runtime.FuncForPC(v.Pointer()).Name()
+	slash := strings.LastIndex(full, "/")
+	dot := strings.Index(full[slash+1:], ".")
+	if dot < 0 {
+		return GoFunc{}, fmt.Errorf("cannot resolve the name of function %q", full)
+	}
+	f := GoFunc{PkgPath: full[:slash+1+dot], Name: full[slash+1+dot+1:]}
+	if strings.ContainsAny(f.Name, ".-") {
+		return GoFunc{}, fmt.Errorf("function %q is not declared at package level", full)
+	}
+	return f, nil
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+func otherTypeKey(pkgPath, ident string) string {
+	return pkgPath + " " + ident
+}
+
+// LookupOtherType returns the mapping registered with RegisterOtherType for the
+// Go type of a field.Other column.
+func LookupOtherType(typ *field.TypeInfo) (OtherType, bool) {
+	if typ == nil || typ.Type != field.TypeOther {
+		return OtherType{}, false
+	}
+	otherTypeRegistryMu.RLock()
+	defer otherTypeRegistryMu.RUnlock()
+	ot, ok := otherTypeRegistry[otherTypeKey(typ.PkgPath, typ.Ident)]
+	if !ok {
+		return OtherType{}, false
+	}
+	return *ot, true
+}
+
+// isOtherTypeMessage reports whether the proto message type name is the
+// protobuf representation of a registered other type.
+func isOtherTypeMessage(pbTypeName string) bool {
+	name := strings.TrimPrefix(pbTypeName, ".")
+	otherTypeRegistryMu.RLock()
+	defer otherTypeRegistryMu.RUnlock()
+	for _, ot := range otherTypeRegistry {
+		if ot.PbTypeName == name {
+			return true
+		}
+	}
+	return false
+}
+
+// resetOtherTypeRegistry clears the registry; intended for tests only.
+func resetOtherTypeRegistry() {
+	otherTypeRegistryMu.Lock()
+	defer otherTypeRegistryMu.Unlock()
+	otherTypeRegistry = map[string]*OtherType{}
+}
@@ -0,0 +1,50 @@
+package entproto
+
+import (
+	"fmt"
+	"slices"
+
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// addRegisteredImports records proto files from the global registry that the file
+// containing the given message must import, e.g. files declaring extensions used
+// in options or well-known types referenced by generated request messages. parse
+// imports and links them.
+func (a *Adapter) addRegisteredImports(msgName string, imports []string) {
+	if len(imports) == 0 {
+		return
+	}
+	if a.registeredImports == nil {
+		a.registeredImports = make(map[string][]string)
+	}
+	for _, imp := range imports {
+		if !slices.Contains(a.registeredImports[msgName], imp) {
+			a.registeredImports[msgName] = append(a.registeredImports[msgName], imp)
+		}
+	}
+}
+
+// loadRegisteredFile resolves a proto file and its transitive dependencies from
+// the global registry into out. The files are fed to desc.CreateFileDescriptors
+// for linking only; like custom type stubs, they are never written to disk.
+func loadRegisteredFile(filePath string, out map[string]*descriptorpb.FileDescriptorProto) error {
+	if _, ok := out[filePath]; ok {
+		return nil
+	}
+	fd, err := protoregistry.GlobalFiles.FindFileByPath(filePath)
+	if err != nil {
+		return fmt.Errorf("entproto: proto file %q is not registered, import its Go package in the generator: %w",
+			filePath, err)
+	}
+	out[filePath] = protodesc.ToFileDescriptorProto(fd)
+	imports := fd.Imports()
+	for i := range imports.Len() {
+		if err := loadRegisteredFile(imports.Get(i).Path(), out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
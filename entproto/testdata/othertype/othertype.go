@@ -0,0 +1,65 @@
+// Package othertype declares field.Other Go types used by the test schemas.
+package othertype
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Money is an amount in cents.
+type Money struct {
+	Cents int64
+}
+
+func (m Money) Value() (driver.Value, error) {
+	return m.Cents, nil
+}
+
+func (m *Money) Scan(src any) error {
+	v, ok := src.(int64)
+	if !ok {
+		return fmt.Errorf("othertype: unexpected money value %T", src)
+	}
+	m.Cents = v
+	return nil
+}
+
+func MoneyToProto(m Money) int64 {
+	return m.Cents
+}
+
+func MoneyFromProto(v int64) Money {
+	return Money{Cents: v}
+}
+
+// Instant is a point in time stored as a unix timestamp.
+type Instant struct {
+	time.Time
+}
+
+func (i Instant) Value() (driver.Value, error) {
+	return i.Unix(), nil
+}
+
+func (i *Instant) Scan(src any) error {
+	v, ok := src.(int64)
+	if !ok {
+		return fmt.Errorf("othertype: unexpected instant value %T", src)
+	}
+	i.Time = time.Unix(v, 0)
+	return nil
+}
+
+func InstantToProto(i Instant) *timestamppb.Timestamp {
+	return timestamppb.New(i.Time)
+}
+
+func InstantFromProto(ts *timestamppb.Timestamp) (Instant, error) {
+	if err := ts.CheckValid(); err != nil {
+		return Instant{}, err
+	}
+	return Instant{Time: ts.AsTime()}, nil
+}
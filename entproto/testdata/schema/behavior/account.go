@@ -0,0 +1,56 @@
+package schema
+
+import (
+	"time"
+
+	"entgo.io/ent"
+	"entgo.io/ent/schema"
+	"entgo.io/ent/schema/field"
+	"github.com/go-sphere/entc-extensions/entproto"
+	"google.golang.org/genproto/googleapis/api/annotations"
+)
+
+type Account struct {
+	ent.Schema
+}
+
+func (Account) Annotations() []schema.Annotation {
+	return []schema.Annotation{
+		entproto.Message(),
+	}
+}
+
+func (Account) Fields() []ent.Field {
+	return []ent.Field{
+		field.String("email").
+			Annotations(entproto.Field(2)),
+		field.String("nickname").
+			Optional().
+			Annotations(entproto.Field(3)),
+		field.String("region").
+			Immutable().
+			Annotations(entproto.Field(4)),
+		field.Time("created_at").
+			Default(time.Now).
+			Immutable().
+			Annotations(entproto.Field(5)),
+		field.Time("updated_at").
+			Default(time.Now).
+			UpdateDefault(time.Now).
+			Annotations(entproto.Field(6)),
+		field.Bool("active").
+			Default(true).
+			Annotations(entproto.Field(7)),
+		field.String("display_name").
+			Optional().
+			Annotations(
+				entproto.Field(8),
+				entproto.FieldBehavior(annotations.FieldBehavior_REQUIRED),
+			),
+		field.String("notes").
+			Annotations(
+				entproto.Field(9),
+				entproto.FieldBehavior(),
+			),
+	}
+}
@@ -0,0 +1,43 @@
+package schema
+
+import (
+	"time"
+
+	"entgo.io/ent"
+	"entgo.io/ent/schema"
+	"entgo.io/ent/schema/field"
+	"github.com/go-sphere/entc-extensions/entproto"
+)
+
+type Task struct {
+	ent.Schema
+}
+
+func (Task) Annotations() []schema.Annotation {
+	return []schema.Annotation{
+		entproto.Message(entproto.WithCRUDMessages()),
+	}
+}
+
+func (Task) Fields() []ent.Field {
+	return []ent.Field{
+		field.String("title").
+			Annotations(entproto.Field(2)),
+		field.Enum("status").
+			Values("todo", "done").
+			Annotations(
+				entproto.Field(3),
+				entproto.Enum(map[string]int32{"todo": 1, "done": 2}),
+			),
+		field.JSON("labels", []string{}).
+			Annotations(entproto.Field(4)),
+		field.Time("created_at").
+			Default(time.Now).
+			Immutable().
+			Annotations(entproto.Field(5)),
+		field.Time("updated_at").
+			Default(time.Now).
+			UpdateDefault(time.Now).
+			Annotations(entproto.Field(6)),
+	}
+}
@@ -0,0 +1,33 @@
+package schema
+
+import (
+	"entgo.io/ent"
+	"entgo.io/ent/schema"
+	"entgo.io/ent/schema/edge"
+	"entgo.io/ent/schema/field"
+	"github.com/go-sphere/entc-extensions/entproto"
+)
+
+type Owner struct {
+	ent.Schema
+}
+
+func (Owner) Annotations() []schema.Annotation {
+	return []schema.Annotation{
+		entproto.Message(entproto.PackageName("acme.owner.v1")),
+	}
+}
+
+func (Owner) Fields() []ent.Field {
+	return []ent.Field{
+		field.String("name").
+			Annotations(entproto.Field(2)),
+	}
+}
+
+func (Owner) Edges() []ent.Edge {
+	return []ent.Edge{
+		edge.To("accounts", Account.Type).
+			Annotations(entproto.Field(3)),
+	}
+}
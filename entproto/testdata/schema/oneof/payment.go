@@ -0,0 +1,39 @@
+package schema
+
+import (
+	"entgo.io/ent"
+	"entgo.io/ent/schema"
+	"entgo.io/ent/schema/field"
+	"github.com/go-sphere/entc-extensions/entproto"
+)
+
+type Payment struct {
+	ent.Schema
+}
+
+func (Payment) Annotations() []schema.Annotation {
+	return []schema.Annotation{
+		entproto.Message(entproto.WithCRUDMessages(entproto.MethodCreate)),
+		entproto.OneOf("payment_method", "card_token", "bank_account_id", "wallet_id"),
+	}
+}
+
+func (Payment) Fields() []ent.Field {
+	return []ent.Field{
+		field.Int64("amount").
+			Annotations(entproto.Field(2)),
+		field.String("note").
+			Optional().
+			Nillable().
+			Annotations(entproto.Field(6, entproto.Optional())),
+		field.String("card_token").
+			Optional().
+			Annotations(entproto.Field(3)),
+		field.Int64("bank_account_id").
+			Optional().
+			Annotations(entproto.Field(4)),
+		field.String("wallet_id").
+			Optional().
+			Annotations(entproto.Field(5)),
+	}
+}
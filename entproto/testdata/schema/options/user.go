@@ -0,0 +1,43 @@
+package schema
+
+import (
+	"entgo.io/ent"
+	"entgo.io/ent/schema"
+	"entgo.io/ent/schema/field"
+	"github.com/go-sphere/entc-extensions/entproto"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+type User struct {
+	ent.Schema
+}
+
+func (User) Annotations() []schema.Annotation {
+	return []schema.Annotation{
+		entproto.Message(),
+		entproto.MessageOptions(&descriptorpb.MessageOptions{Deprecated: proto.Bool(true)}),
+	}
+}
+
+func (User) Fields() []ent.Field {
+	outputOnly := &descriptorpb.FieldOptions{}
+	proto.SetExtension(outputOnly, annotations.E_FieldBehavior, []annotations.FieldBehavior{
+		annotations.FieldBehavior_OUTPUT_ONLY,
+	})
+	return []ent.Field{
+		field.String("name").
+			Annotations(entproto.Field(2)),
+		field.String("legacy_name").
+			Annotations(
+				entproto.Field(3),
+				entproto.FieldOptions(&descriptorpb.FieldOptions{Deprecated: proto.Bool(true)}),
+			),
+		field.String("etag").
+			Annotations(
+				entproto.Field(4),
+				entproto.FieldOptions(outputOnly),
+			),
+	}
+}
@@ -0,0 +1,49 @@
+package schema
+
+import (
+	"entgo.io/ent"
+	"entgo.io/ent/schema"
+	"entgo.io/ent/schema/field"
+	"github.com/go-sphere/entc-extensions/entproto"
+	"github.com/go-sphere/entc-extensions/entproto/testdata/othertype"
+)
+
+type Order struct {
+	ent.Schema
+}
+
+func (Order) Annotations() []schema.Annotation {
+	return []schema.Annotation{
+		entproto.Message(),
+	}
+}
+
+func (Order) Fields() []ent.Field {
+	return []ent.Field{
+		field.Other("total", othertype.Money{}).
+			SchemaType(map[string]string{"postgres": "bigint"}).
+			Annotations(entproto.Field(2)),
+		field.Other("placed_at", othertype.Instant{}).
+			SchemaType(map[string]string{"postgres": "bigint"}).
+			Annotations(entproto.Field(3)),
+	}
+}
+
+type OrderTotals struct {
+	ent.View
+}
+
+func (OrderTotals) Annotations() []schema.Annotation {
+	return []schema.Annotation{
+		entproto.Message(),
+	}
+}
+
+func (OrderTotals) Fields() []ent.Field {
+	return []ent.Field{
+		field.String("customer").
+			Annotations(entproto.Field(2)),
+		field.Int64("orders").
+			Annotations(entproto.Field(3)),
+	}
+}
@@ -1,13 +1,27 @@
 package main
 
 import (
+	"flag"
 	"log"
 
 	"github.com/go-sphere/entc-extensions/entconv"
+	"github.com/go-sphere/entc-extensions/entproto"
+	"github.com/go-sphere/entc-extensions/testdata/internal/pkg/conv"
 )
 
 func main() {
-	if err := entconv.GenerateConverterFileWithOptions(); err != nil {
+	check := flag.Bool("check", false, "fail with a diff when the converters are out of date instead of writing them")
+	flag.Parse()
+
+	entproto.RegisterOtherType(conv.ToProtoMoney, conv.ToEntMoney)
+	if err := entconv.GenerateConverterFileWithOptions(
+		entconv.WithStrict(true),
+		entconv.WithTests(true),
+		entconv.WithCheck(*check),
+		entconv.WithFieldConverter("Profile", "address", conv.ToProtoAddress, conv.ToEntAddress),
+		entconv.WithFieldTimeFormat("Device", "created_at", entconv.TimeUnixMilli),
+		entconv.WithWarningHandler(func(err error) { log.Printf("warning: %v", err) }),
+	); err != nil {
 		log.Fatalf("error: %v", err)
 	}
 }
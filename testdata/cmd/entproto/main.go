@@ -10,12 +10,14 @@ import (
 	"entgo.io/ent/entc/gen"
 	"entgo.io/ent/schema/field"
 	"github.com/go-sphere/entc-extensions/entproto"
+	"github.com/go-sphere/entc-extensions/testdata/internal/pkg/conv"
 )
 
 func main() {
 	schema := flag.String("schema", "./internal/pkg/database/schema", "path to the schema directory")
 	target := flag.String("target", "./internal/pkg/database/ent", "target directory for generated code")
 	flag.Parse()
+	entproto.RegisterOtherType(conv.ToProtoMoney, conv.ToEntMoney)
 	ex, err := entproto.NewExtension(
 		entproto.WithProtoDir("./proto"),
 	)
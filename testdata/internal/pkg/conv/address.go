@@ -0,0 +1,27 @@
+package conv
+
+import "encoding/json"
+
+// Address is a postal address, stored in a JSON column and exchanged as a
+// JSON string.
+type Address struct {
+	Street string `json:"street,omitempty"`
+	City   string `json:"city,omitempty"`
+}
+
+func ToProtoAddress(a Address) string {
+	if a == (Address{}) {
+		return ""
+	}
+	b, _ := json.Marshal(a)
+	return string(b)
+}
+
+func ToEntAddress(v string) (Address, error) {
+	var a Address
+	if v == "" {
+		return a, nil
+	}
+	err := json.Unmarshal([]byte(v), &a)
+	return a, err
+}
@@ -0,0 +1,35 @@
+package conv
+
+import (
+	"database/sql/driver"
+	"fmt"
+)
+
+// Money is an amount in cents, stored in a field.Other column.
+type Money struct {
+	Cents int64
+}
+
+func (m Money) Value() (driver.Value, error) {
+	return m.Cents, nil
+}
+
+func (m *Money) Scan(src any) error {
+	v, ok := src.(int64)
+	if !ok {
+		return fmt.Errorf("conv: unexpected money value %T", src)
+	}
+	m.Cents = v
+	return nil
+}
+
+func ToProtoMoney(m Money) int64 {
+	return m.Cents
+}
+
+func ToEntMoney(v int64) (Money, error) {
+	if v < 0 {
+		return Money{}, fmt.Errorf("conv: negative money amount %d", v)
+	}
+	return Money{Cents: v}, nil
+}
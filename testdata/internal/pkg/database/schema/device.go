@@ -0,0 +1,66 @@
+package schema
+
+import (
+	"entgo.io/ent"
+	"entgo.io/ent/schema"
+	"entgo.io/ent/schema/field"
+	"github.com/go-sphere/entc-extensions/entproto"
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// DeviceSettings is stored in a JSON column and converted to its JSON encoding.
+type DeviceSettings struct {
+	Theme         string `json:"theme"`
+	Notifications bool   `json:"notifications"`
+}
+
+// Device covers UUID IDs and fields, converted to proto strings and bytes,
+// the time formats other than Unix seconds, JSON encoded fields and external
+// message fields.
+type Device struct {
+	ent.Schema
+}
+
+func (Device) Annotations() []schema.Annotation {
+	return []schema.Annotation{
+		entproto.Message(),
+	}
+}
+
+func (Device) Fields() []ent.Field {
+	return []ent.Field{
+		field.UUID("id", uuid.UUID{}).
+			Default(uuid.New).
+			Annotations(entproto.Field(1, entproto.Type(descriptorpb.FieldDescriptorProto_TYPE_STRING))),
+		field.String("name").
+			Annotations(entproto.Field(2)),
+		field.UUID("token", uuid.UUID{}).
+			Annotations(entproto.Field(3)),
+		field.UUID("parent_id", uuid.UUID{}).
+			Optional().
+			Nillable().
+			Annotations(entproto.Field(4, entproto.Optional(), entproto.Type(descriptorpb.FieldDescriptorProto_TYPE_STRING))),
+		field.Time("created_at").
+			Annotations(entproto.Field(5)),
+		field.Time("expires_at").
+			Annotations(entproto.Field(6, entproto.Type(descriptorpb.FieldDescriptorProto_TYPE_STRING))),
+		field.Time("last_seen_at").
+			Optional().
+			Nillable().
+			Annotations(entproto.MessageField(7, &timestamppb.Timestamp{})),
+		field.JSON("settings", DeviceSettings{}).
+			Annotations(entproto.Field(8, entproto.EncodeJSON(descriptorpb.FieldDescriptorProto_TYPE_BYTES))),
+		field.JSON("labels", map[string]string{}).
+			Optional().
+			Annotations(entproto.Field(9, entproto.EncodeJSON(descriptorpb.FieldDescriptorProto_TYPE_STRING))),
+		field.JSON("heartbeat_interval", &durationpb.Duration{}).
+			Optional().
+			Annotations(entproto.MessageField(10, &durationpb.Duration{})),
+		field.JSON("boot_times", []*timestamppb.Timestamp{}).
+			Optional().
+			Annotations(entproto.MessageField(11, &timestamppb.Timestamp{})),
+	}
+}
@@ -0,0 +1,61 @@
+package schema
+
+import (
+	"entgo.io/ent"
+	"entgo.io/ent/dialect"
+	"entgo.io/ent/schema"
+	"entgo.io/ent/schema/field"
+	"github.com/go-sphere/entc-extensions/entproto"
+	"github.com/go-sphere/entc-extensions/testdata/internal/pkg/conv"
+)
+
+type Payment struct {
+	ent.Schema
+}
+
+func (Payment) Annotations() []schema.Annotation {
+	return []schema.Annotation{
+		entproto.Message(),
+		entproto.OneOf("payment_method", "card_token", "bank_account_id", "wallet_id"),
+	}
+}
+
+func (Payment) Fields() []ent.Field {
+	return []ent.Field{
+		field.Int64("amount").
+			Annotations(entproto.Field(2)),
+		field.String("card_token").
+			Optional().
+			Annotations(entproto.Field(3)),
+		field.Int64("bank_account_id").
+			Optional().
+			Annotations(entproto.Field(4)),
+		field.String("wallet_id").
+			Optional().
+			Nillable().
+			Annotations(entproto.Field(5)),
+		field.Other("fee", conv.Money{}).
+			SchemaType(map[string]string{dialect.Postgres: "bigint", dialect.MySQL: "bigint", dialect.SQLite: "integer"}).
+			Annotations(entproto.Field(6)),
+	}
+}
+
+// PaymentTotal is a read-only view aggregating payments per method.
+type PaymentTotal struct {
+	ent.View
+}
+
+func (PaymentTotal) Annotations() []schema.Annotation {
+	return []schema.Annotation{
+		entproto.Message(),
+	}
+}
+
+func (PaymentTotal) Fields() []ent.Field {
+	return []ent.Field{
+		field.String("method").
+			Annotations(entproto.Field(2)),
+		field.Int64("amount").
+			Annotations(entproto.Field(3)),
+	}
+}
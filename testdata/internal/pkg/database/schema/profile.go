@@ -0,0 +1,67 @@
+package schema
+
+import (
+	"entgo.io/ent"
+	"entgo.io/ent/dialect"
+	"entgo.io/ent/schema"
+	"entgo.io/ent/schema/field"
+	"github.com/go-sphere/entc-extensions/entproto"
+	"github.com/go-sphere/entc-extensions/testdata/internal/pkg/conv"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Profile covers the combinations of Optional and Nillable ent fields with
+// plain and proto3 optional proto fields.
+type Profile struct {
+	ent.Schema
+}
+
+func (Profile) Annotations() []schema.Annotation {
+	return []schema.Annotation{
+		entproto.Message(),
+	}
+}
+
+func (Profile) Fields() []ent.Field {
+	return []ent.Field{
+		field.String("nickname").
+			Optional().
+			Nillable().
+			Annotations(entproto.Field(2, entproto.Optional())),
+		field.String("bio").
+			Optional().
+			Nillable().
+			Annotations(entproto.Field(3)),
+		field.Int("age").
+			Optional().
+			Annotations(entproto.Field(4, entproto.Optional())),
+		field.Int("score").
+			Optional().
+			Annotations(entproto.Field(5)),
+		field.Int32("level").
+			Annotations(entproto.Field(6, entproto.Optional())),
+		field.Time("birthday").
+			Optional().
+			Nillable().
+			Annotations(entproto.Field(7)),
+		field.Enum("visibility").
+			Values("public", "private").
+			Optional().
+			Nillable().
+			Annotations(
+				entproto.Field(8, entproto.Optional()),
+				entproto.Enum(map[string]int32{
+					"public":  1,
+					"private": 2,
+				}),
+			),
+		field.Other("balance", conv.Money{}).
+			SchemaType(map[string]string{dialect.Postgres: "bigint", dialect.MySQL: "bigint", dialect.SQLite: "integer"}).
+			Optional().
+			Nillable().
+			Annotations(entproto.Field(9, entproto.Optional())),
+		field.JSON("address", conv.Address{}).
+			Optional().
+			Annotations(entproto.Field(10, entproto.Type(descriptorpb.FieldDescriptorProto_TYPE_STRING))),
+	}
+}
@@ -48,6 +48,10 @@ func (User) Fields() []ent.Field {
 			Annotations(entproto.Field(14)),
 		field.JSON("points", []int64{}).
 			Annotations(entproto.Field(15)),
+		field.String("password_hash").
+			Optional().
+			Sensitive().
+			Annotations(entproto.Field(18)),
 	}
 }
 
@@ -1,14 +1,25 @@
 package tests
 
 import (
+	"errors"
+	"fmt"
+	"maps"
 	"testing"
 	"time"
 
 	"github.com/go-sphere/entc-extensions/testdata/api/entpb"
+	"github.com/go-sphere/entc-extensions/testdata/internal/pkg/conv"
 	"github.com/go-sphere/entc-extensions/testdata/internal/pkg/database/ent"
 	"github.com/go-sphere/entc-extensions/testdata/internal/pkg/database/ent/post"
+	"github.com/go-sphere/entc-extensions/testdata/internal/pkg/database/ent/profile"
+	"github.com/go-sphere/entc-extensions/testdata/internal/pkg/database/schema"
 	"github.com/go-sphere/entc-extensions/testdata/internal/pkg/render/entbind"
 	"github.com/go-sphere/entc-extensions/testdata/internal/pkg/render/entmap"
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 func TestGeneratedSymbolsExist(t *testing.T) {
@@ -16,9 +27,17 @@ func TestGeneratedSymbolsExist(t *testing.T) {
 	_ = entbind.UpdateOneGroup
 	_ = entbind.CreatePost
 	_ = entbind.CreateUser
+	_ = entmap.ToEntDevice
+	_ = entmap.ToProtoDevice
 	_ = entmap.ToEntGroup
 	_ = entmap.ToProtoGroup
+	_ = entmap.ToEntPayment
+	_ = entmap.ToProtoPayment
+	_ = entmap.ToEntPaymentTotal
+	_ = entmap.ToProtoPaymentTotal
 	_ = entmap.ToEntPost
+	_ = entmap.ToEntProfile
+	_ = entmap.ToProtoProfile
 	_ = entmap.ToEntUser
 	_ = entmap.ToProtoPost
 	_ = entmap.ToProtoUser
@@ -122,3 +141,591 @@ func TestEntconvRoundTrip(t *testing.T) {
 		t.Fatalf("[entconv] group round-trip mismatch: %+v -> %+v", entGroup, backGroup)
 	}
 }
+
+func TestEntconvOneOfRoundTrip(t *testing.T) {
+	walletID := "w-1"
+	tests := map[string]struct {
+		ent  *ent.Payment
+		want any
+	}{
+		"card":   {ent: &ent.Payment{ID: 1, Amount: 100, CardToken: "tok"}, want: &entpb.Payment_CardToken{}},
+		"bank":   {ent: &ent.Payment{ID: 2, Amount: 200, BankAccountID: 9}, want: &entpb.Payment_BankAccountId{}},
+		"wallet": {ent: &ent.Payment{ID: 3, Amount: 300, WalletID: &walletID}, want: &entpb.Payment_WalletId{}},
+		"none":   {ent: &ent.Payment{ID: 4, Amount: 400}},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			pbPayment, err := entmap.ToProtoPayment(tt.ent)
+			if err != nil {
+				t.Fatalf("[entconv] ToProtoPayment failed: %v", err)
+			}
+			if got, want := fmt.Sprintf("%T", pbPayment.PaymentMethod), fmt.Sprintf("%T", tt.want); got != want {
+				t.Fatalf("[entconv] payment_method=%s, want %s", got, want)
+			}
+			back, err := entmap.ToEntPayment(pbPayment)
+			if err != nil {
+				t.Fatalf("[entconv] ToEntPayment failed: %v", err)
+			}
+			if back.Amount != tt.ent.Amount || back.CardToken != tt.ent.CardToken || back.BankAccountID != tt.ent.BankAccountID {
+				t.Fatalf("[entconv] payment round-trip mismatch: %+v -> %+v", tt.ent, back)
+			}
+			if (back.WalletID == nil) != (tt.ent.WalletID == nil) || back.WalletID != nil && *back.WalletID != *tt.ent.WalletID {
+				t.Fatalf("[entconv] payment wallet_id round-trip mismatch: %v -> %v", tt.ent.WalletID, back.WalletID)
+			}
+		})
+	}
+}
+
+func TestEntconvOtherTypeAndViewRoundTrip(t *testing.T) {
+	entPayment := &ent.Payment{ID: 5, Amount: 500, Fee: conv.Money{Cents: 25}}
+	pbPayment, err := entmap.ToProtoPayment(entPayment)
+	if err != nil {
+		t.Fatalf("[entconv] ToProtoPayment failed: %v", err)
+	}
+	if pbPayment.Fee != 25 {
+		t.Fatalf("[entconv] payment fee=%d, want 25", pbPayment.Fee)
+	}
+	back, err := entmap.ToEntPayment(pbPayment)
+	if err != nil {
+		t.Fatalf("[entconv] ToEntPayment failed: %v", err)
+	}
+	if back.Fee != entPayment.Fee {
+		t.Fatalf("[entconv] payment fee round-trip mismatch: %v -> %v", entPayment.Fee, back.Fee)
+	}
+	pbPayment.Fee = -1
+	if _, err := entmap.ToEntPayment(pbPayment); err == nil {
+		t.Fatalf("[entconv] expected ToEntPayment to return the fee conversion error")
+	}
+
+	entTotal := &ent.PaymentTotal{Method: "card", Amount: 1200}
+	pbTotal, err := entmap.ToProtoPaymentTotal(entTotal)
+	if err != nil {
+		t.Fatalf("[entconv] ToProtoPaymentTotal failed: %v", err)
+	}
+	backTotal, err := entmap.ToEntPaymentTotal(pbTotal)
+	if err != nil {
+		t.Fatalf("[entconv] ToEntPaymentTotal failed: %v", err)
+	}
+	if backTotal.Method != entTotal.Method || backTotal.Amount != entTotal.Amount {
+		t.Fatalf("[entconv] payment total round-trip mismatch: %+v -> %+v", entTotal, backTotal)
+	}
+}
+
+func TestEntconvEdgesRoundTrip(t *testing.T) {
+	alice := &ent.User{ID: 1, Name: "alice"}
+	first := &ent.Post{ID: 10, Title: "first", Status: post.StatusDone}
+	second := &ent.Post{ID: 11, Title: "second", Status: post.StatusPending}
+	staff := &ent.Group{ID: 20, Name: "staff"}
+	// Bidirectional edges as loaded by WithPosts(func(q) { q.WithAuthor() }) point back to the user.
+	first.Edges.Author = alice
+	second.Edges.Author = alice
+	staff.Edges.Users = []*ent.User{alice}
+	alice.Edges.Posts = []*ent.Post{first, second}
+	alice.Edges.Groups = []*ent.Group{staff}
+
+	pbUser, err := entmap.ToProtoUser(alice)
+	if err != nil {
+		t.Fatalf("[entconv] ToProtoUser failed: %v", err)
+	}
+	if len(pbUser.Posts) != 2 || pbUser.Posts[0].Title != "first" || pbUser.Posts[1].Status != entpb.Post_STATUS_PENDING {
+		t.Fatalf("[entconv] user posts edge not converted: %v", pbUser.Posts)
+	}
+	if len(pbUser.Groups) != 1 || pbUser.Groups[0].Name != "staff" {
+		t.Fatalf("[entconv] user groups edge not converted: %v", pbUser.Groups)
+	}
+	// The author of a post is the user being converted: it is converted without its edges.
+	author := pbUser.Posts[0].Author
+	if author == nil || author.Name != "alice" || author.Posts != nil || author.Groups != nil {
+		t.Fatalf("[entconv] cycle not cut at post author: %v", author)
+	}
+
+	unloaded, err := entmap.ToProtoPost(&ent.Post{ID: 12, Title: "unloaded", Status: post.StatusDone})
+	if err != nil {
+		t.Fatalf("[entconv] ToProtoPost failed: %v", err)
+	}
+	if unloaded.Author != nil {
+		t.Fatalf("[entconv] unloaded edge should stay nil, got %v", unloaded.Author)
+	}
+
+	back, err := entmap.ToEntUser(pbUser)
+	if err != nil {
+		t.Fatalf("[entconv] ToEntUser failed: %v", err)
+	}
+	if len(back.Edges.Posts) != 2 || back.Edges.Posts[1].Status != post.StatusPending {
+		t.Fatalf("[entconv] user posts edge not filled: %v", back.Edges.Posts)
+	}
+	if len(back.Edges.Groups) != 1 || back.Edges.Groups[0].Name != "staff" {
+		t.Fatalf("[entconv] user groups edge not filled: %v", back.Edges.Groups)
+	}
+	if a := back.Edges.Posts[0].Edges.Author; a == nil || a.ID != alice.ID {
+		t.Fatalf("[entconv] post author edge not filled: %v", a)
+	}
+}
+
+func TestEntconvEdgesDepthLimit(t *testing.T) {
+	// A chain deeper than the default edge depth of 3.
+	root := &ent.User{ID: 1}
+	curr := root
+	for i := 2; i <= 6; i++ {
+		next := &ent.User{ID: int64(i)}
+		curr.Edges.Groups = []*ent.Group{{ID: int64(100 + i), Edges: ent.GroupEdges{Users: []*ent.User{next}}}}
+		curr = next
+	}
+	pbUser, err := entmap.ToProtoUser(root)
+	if err != nil {
+		t.Fatalf("[entconv] ToProtoUser failed: %v", err)
+	}
+	// user(1) -> group -> user(2) -> group: the fourth level is not converted.
+	group := pbUser.Groups[0].Users[0].Groups[0]
+	if group.Users != nil {
+		t.Fatalf("[entconv] edges converted past the maximum depth: %v", group.Users)
+	}
+}
+
+func TestEntconvNillableOptionalRoundTrip(t *testing.T) {
+	empty, bio := "", "hello"
+	birthday := time.Unix(1_723_456_789, 0)
+	visibility := profile.VisibilityPrivate
+	balance := conv.Money{Cents: 0}
+	entProfile := &ent.Profile{
+		ID:         7,
+		Nickname:   &empty,
+		Bio:        &bio,
+		Age:        33,
+		Score:      12,
+		Level:      0,
+		Birthday:   &birthday,
+		Visibility: &visibility,
+		Balance:    &balance,
+	}
+
+	pbProfile, err := entmap.ToProtoProfile(entProfile)
+	if err != nil {
+		t.Fatalf("[entconv] ToProtoProfile failed: %v", err)
+	}
+	// Set Nillable values keep their presence in proto3 optional fields, even when zero.
+	if pbProfile.Nickname == nil || *pbProfile.Nickname != "" ||
+		pbProfile.Balance == nil || *pbProfile.Balance != 0 ||
+		pbProfile.Level == nil || *pbProfile.Level != 0 ||
+		pbProfile.Age == nil || *pbProfile.Age != 33 ||
+		pbProfile.Visibility == nil || *pbProfile.Visibility != entpb.Profile_VISIBILITY_PRIVATE {
+		t.Fatalf("[entconv] proto3 optional fields lost their presence: %v", pbProfile)
+	}
+	back, err := entmap.ToEntProfile(pbProfile)
+	if err != nil {
+		t.Fatalf("[entconv] ToEntProfile failed: %v", err)
+	}
+	if !equalPtr(back.Nickname, entProfile.Nickname) || !equalPtr(back.Bio, entProfile.Bio) ||
+		!equalPtr(back.Visibility, entProfile.Visibility) || !equalPtr(back.Balance, entProfile.Balance) ||
+		back.Birthday == nil || !back.Birthday.Equal(birthday) ||
+		back.Age != entProfile.Age || back.Score != entProfile.Score || back.Level != entProfile.Level {
+		t.Fatalf("[entconv] profile round-trip mismatch: %+v -> %+v", entProfile, back)
+	}
+
+	unset := &ent.Profile{ID: 8}
+	pbUnset, err := entmap.ToProtoProfile(unset)
+	if err != nil {
+		t.Fatalf("[entconv] ToProtoProfile failed: %v", err)
+	}
+	// Unset Nillable values and zero Optional values are absent; required fields are always present.
+	if pbUnset.Nickname != nil || pbUnset.Balance != nil || pbUnset.Visibility != nil || pbUnset.Age != nil {
+		t.Fatalf("[entconv] unset fields should be absent: %v", pbUnset)
+	}
+	if pbUnset.Level == nil {
+		t.Fatalf("[entconv] required proto3 optional field level should be present")
+	}
+	backUnset, err := entmap.ToEntProfile(pbUnset)
+	if err != nil {
+		t.Fatalf("[entconv] ToEntProfile failed: %v", err)
+	}
+	if backUnset.Nickname != nil || backUnset.Balance != nil || backUnset.Visibility != nil ||
+		backUnset.Age != 0 || backUnset.Score != 0 || backUnset.Level != 0 {
+		t.Fatalf("[entconv] unset profile round-trip mismatch: %+v", backUnset)
+	}
+	// Without proto3 presence, Nillable fields come back pointing to the zero value.
+	if backUnset.Bio == nil || *backUnset.Bio != "" || backUnset.Birthday == nil {
+		t.Fatalf("[entconv] plain Nillable fields should be set to the zero value: %+v", backUnset)
+	}
+}
+
+func TestEntconvStrictConversionErrors(t *testing.T) {
+	tests := map[string]struct {
+		convert func() error
+		field   string
+	}{
+		"int8 overflow": {
+			convert: func() error { _, err := entmap.ToEntUser(&entpb.User{Rank: 300}); return err },
+			field:   "rank",
+		},
+		"uint32 overflow": {
+			convert: func() error { _, err := entmap.ToProtoUser(&ent.User{Role: 1 << 40}); return err },
+			field:   "role",
+		},
+		"unknown pb enum": {
+			convert: func() error { _, err := entmap.ToEntPost(&entpb.Post{Status: 99}); return err },
+			field:   "status",
+		},
+		"unspecified required enum": {
+			convert: func() error { _, err := entmap.ToEntPost(&entpb.Post{}); return err },
+			field:   "status",
+		},
+		"unknown ent enum": {
+			convert: func() error { _, err := entmap.ToProtoPost(&ent.Post{Status: "archived"}); return err },
+			field:   "status",
+		},
+		"two oneof fields": {
+			convert: func() error {
+				_, err := entmap.ToProtoPayment(&ent.Payment{CardToken: "tok", BankAccountID: 9})
+				return err
+			},
+			field: "payment_method",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var convErr *entmap.ConversionError
+			if err := tt.convert(); !errors.As(err, &convErr) {
+				t.Fatalf("[entconv] error=%v, want a *ConversionError", err)
+			}
+			if convErr.Field != tt.field {
+				t.Fatalf("[entconv] ConversionError.Field=%q, want %q", convErr.Field, tt.field)
+			}
+		})
+	}
+
+	// The zero value of optional enums is not an error.
+	pbProfile, err := entmap.ToProtoProfile(&ent.Profile{})
+	if err != nil {
+		t.Fatalf("[entconv] ToProtoProfile failed: %v", err)
+	}
+	pbProfile.Visibility = new(entpb.Profile_Visibility)
+	if _, err := entmap.ToEntProfile(pbProfile); err != nil {
+		t.Fatalf("[entconv] ToEntProfile failed for an unspecified optional enum: %v", err)
+	}
+}
+
+func TestEntconvFieldConverter(t *testing.T) {
+	address := conv.Address{Street: "1 Main St", City: "Springfield"}
+	pbProfile, err := entmap.ToProtoProfile(&ent.Profile{Address: address})
+	if err != nil {
+		t.Fatalf("[entconv] ToProtoProfile failed: %v", err)
+	}
+	if want := `{"street":"1 Main St","city":"Springfield"}`; pbProfile.Address != want {
+		t.Fatalf("[entconv] address=%q, want %q", pbProfile.Address, want)
+	}
+	back, err := entmap.ToEntProfile(pbProfile)
+	if err != nil {
+		t.Fatalf("[entconv] ToEntProfile failed: %v", err)
+	}
+	if back.Address != address {
+		t.Fatalf("[entconv] address round-trip mismatch: %+v -> %+v", address, back.Address)
+	}
+
+	// Errors of the user conversion function are returned.
+	if _, err := entmap.ToEntProfile(&entpb.Profile{Address: "{"}); err == nil {
+		t.Fatal("[entconv] ToEntProfile should fail for an invalid address")
+	}
+}
+
+func TestEntconvFieldMask(t *testing.T) {
+	author := &ent.User{ID: 1, Name: "alice", Email: "alice@example.com"}
+	entPost := &ent.Post{ID: 2, Title: "hello", Content: "world", Status: post.StatusDone, Likes: 3}
+	entPost.Edges.Author = author
+	author.Edges.Posts = []*ent.Post{entPost}
+
+	pbPost, err := entmap.ToProtoPostMasked(entPost, &fieldmaskpb.FieldMask{Paths: []string{"title", "author.name"}})
+	if err != nil {
+		t.Fatalf("[entconv] ToProtoPostMasked failed: %v", err)
+	}
+	if pbPost.Title != "hello" || pbPost.Content != "" || pbPost.Likes != 0 || pbPost.Id != 0 {
+		t.Fatalf("[entconv] masked post should only hold its title: %v", pbPost)
+	}
+	if pbPost.Author == nil || pbPost.Author.Name != "alice" || pbPost.Author.Email != "" || pbPost.Author.Posts != nil {
+		t.Fatalf("[entconv] masked author should only hold its name: %v", pbPost.Author)
+	}
+	full, err := entmap.ToProtoPostMasked(entPost, nil)
+	if err != nil || full.Content != "world" || full.Author == nil || full.Author.Email != "alice@example.com" {
+		t.Fatalf("[entconv] an empty mask should keep all fields: %v, %v", full, err)
+	}
+
+	var maskErr *entmap.FieldMaskError
+	for _, path := range []string{"nope", "title.length", "author.nope"} {
+		_, err := entmap.ToProtoPostMasked(entPost, &fieldmaskpb.FieldMask{Paths: []string{path}})
+		if !errors.As(err, &maskErr) || maskErr.Path != path {
+			t.Fatalf("[entconv] ToProtoPostMasked(%q) error=%v, want a *FieldMaskError", path, err)
+		}
+	}
+
+	// Unmasked fields are neither converted nor changed: the unspecified status would fail in strict mode.
+	dst := &ent.Post{ID: 2, Title: "old", Content: "kept", Status: post.StatusPending, Likes: 9}
+	update := &entpb.Post{Title: "new", Likes: 0, Author: &entpb.User{Name: "bob"}}
+	mask := &fieldmaskpb.FieldMask{Paths: []string{"title", "likes", "author.name"}}
+	if err := entmap.MergeToEntPost(dst, update, mask); err != nil {
+		t.Fatalf("[entconv] MergeToEntPost failed: %v", err)
+	}
+	if dst.Title != "new" || dst.Likes != 0 || dst.Content != "kept" || dst.Status != post.StatusPending {
+		t.Fatalf("[entconv] merged post mismatch: %+v", dst)
+	}
+	if dst.Edges.Author == nil || dst.Edges.Author.Name != "bob" {
+		t.Fatalf("[entconv] author sub-path should merge into the edge: %+v", dst.Edges.Author)
+	}
+	err = entmap.MergeToEntUser(&ent.User{}, &entpb.User{}, &fieldmaskpb.FieldMask{Paths: []string{"posts.title"}})
+	if !errors.As(err, &maskErr) || maskErr.Path != "posts.title" {
+		t.Fatalf("[entconv] merging a sub-path of a non-unique edge error=%v, want a *FieldMaskError", err)
+	}
+}
+
+func TestEntconvRedactsSensitiveFields(t *testing.T) {
+	entUser := &ent.User{ID: 1, Name: "alice", PasswordHash: "secret"}
+	pbUser, err := entmap.ToProtoUser(entUser)
+	if err != nil {
+		t.Fatalf("[entconv] ToProtoUser failed: %v", err)
+	}
+	if pbUser.PasswordHash != "" || pbUser.Name != "alice" {
+		t.Fatalf("[entconv] ToProtoUser should redact password_hash: %v", pbUser)
+	}
+	unredacted, err := entmap.ToProtoUserUnredacted(entUser)
+	if err != nil {
+		t.Fatalf("[entconv] ToProtoUserUnredacted failed: %v", err)
+	}
+	if unredacted.PasswordHash != "secret" || unredacted.Name != "alice" {
+		t.Fatalf("[entconv] ToProtoUserUnredacted should keep password_hash: %v", unredacted)
+	}
+	back, err := entmap.ToEntUser(unredacted)
+	if err != nil || back.PasswordHash != "secret" {
+		t.Fatalf("[entconv] ToEntUser should convert password_hash: %+v, %v", back, err)
+	}
+}
+
+func TestEntconvListAndMapConverters(t *testing.T) {
+	users := ent.Users{{ID: 1, Name: "alice"}, {ID: 2, Name: "bob"}}
+
+	pbUsers, err := entmap.ToProtoUserList(users)
+	if err != nil {
+		t.Fatalf("[entconv] ToProtoUserList failed: %v", err)
+	}
+	if len(pbUsers) != 2 || pbUsers[0].Name != "alice" || pbUsers[1].Name != "bob" {
+		t.Fatalf("[entconv] ToProtoUserList=%v", pbUsers)
+	}
+	generic, err := entmap.ConvertList(users, entmap.ToProtoUser)
+	if err != nil || len(generic) != 2 || generic[1].Id != 2 {
+		t.Fatalf("[entconv] ConvertList=%v, %v", generic, err)
+	}
+	back, err := entmap.ToEntUserList(pbUsers)
+	if err != nil || len(back) != 2 || back[0].Name != "alice" {
+		t.Fatalf("[entconv] ToEntUserList=%v, %v", back, err)
+	}
+	if nilList, err := entmap.ToProtoUserList(nil); nilList != nil || err != nil {
+		t.Fatalf("[entconv] ToProtoUserList(nil)=%v, %v, want nil", nilList, err)
+	}
+
+	byID, err := entmap.ToProtoUserMap(append(users, nil))
+	if err != nil || len(byID) != 2 || byID[2].Name != "bob" {
+		t.Fatalf("[entconv] ToProtoUserMap=%v, %v", byID, err)
+	}
+	entByID, err := entmap.ToEntUserMap(append(pbUsers, nil))
+	if err != nil || len(entByID) != 2 || entByID[1].Name != "alice" {
+		t.Fatalf("[entconv] ToEntUserMap=%v, %v", entByID, err)
+	}
+
+	// Errors stop the conversion.
+	pbUsers[1].Rank = 300
+	if _, err := entmap.ToEntUserList(pbUsers); err == nil {
+		t.Fatalf("[entconv] expected ToEntUserList to return the rank conversion error")
+	}
+	if _, err := entmap.ToEntUserMap(pbUsers); err == nil {
+		t.Fatalf("[entconv] expected ToEntUserMap to return the rank conversion error")
+	}
+
+	// Views have no ID, so they only get the list converters.
+	totals, err := entmap.ToProtoPaymentTotalList([]*ent.PaymentTotal{{Method: "card", Amount: 10}})
+	if err != nil || len(totals) != 1 || totals[0].Method != "card" {
+		t.Fatalf("[entconv] ToProtoPaymentTotalList=%v, %v", totals, err)
+	}
+}
+
+func TestEntconvUUIDRoundTrip(t *testing.T) {
+	parent := uuid.MustParse("0b1e4a52-6a53-4c2e-9d0f-3a4a1f8c2b11")
+	entDevice := &ent.Device{
+		ID:       uuid.MustParse("7d444840-9dc0-11d1-b245-5ffdce74fad2"),
+		Name:     "phone",
+		Token:    uuid.MustParse("f47ac10b-58cc-4372-a567-0e02b2c3d479"),
+		ParentID: &parent,
+	}
+	pbDevice, err := entmap.ToProtoDevice(entDevice)
+	if err != nil {
+		t.Fatalf("[entconv] ToProtoDevice failed: %v", err)
+	}
+	if pbDevice.Id != "7d444840-9dc0-11d1-b245-5ffdce74fad2" || len(pbDevice.Token) != 16 || pbDevice.GetParentId() != parent.String() {
+		t.Fatalf("[entconv] unexpected pb device: %v", pbDevice)
+	}
+	back, err := entmap.ToEntDevice(pbDevice)
+	if err != nil {
+		t.Fatalf("[entconv] ToEntDevice failed: %v", err)
+	}
+	if back.ID != entDevice.ID || back.Token != entDevice.Token || !equalPtr(back.ParentID, entDevice.ParentID) {
+		t.Fatalf("[entconv] device round-trip mismatch: %+v -> %+v", entDevice, back)
+	}
+	byID, err := entmap.ToProtoDeviceMap([]*ent.Device{entDevice})
+	if err != nil || byID[entDevice.ID] == nil {
+		t.Fatalf("[entconv] ToProtoDeviceMap=%v, %v", byID, err)
+	}
+
+	// The zero UUID converts to the empty pb value and back.
+	zero, err := entmap.ToProtoDevice(&ent.Device{Name: "new"})
+	if err != nil || zero.Id != "" || zero.Token != nil || zero.ParentId != nil {
+		t.Fatalf("[entconv] ToProtoDevice of zero UUIDs=%v, %v", zero, err)
+	}
+	if back, err := entmap.ToEntDevice(zero); err != nil || back.ID != uuid.Nil || back.Token != uuid.Nil {
+		t.Fatalf("[entconv] ToEntDevice of empty UUIDs=%+v, %v", back, err)
+	}
+
+	// Malformed UUIDs are returned as conversion errors.
+	var convErr *entmap.ConversionError
+	if _, err := entmap.ToEntDevice(&entpb.Device{Id: "not-a-uuid"}); !errors.As(err, &convErr) || convErr.Field != "id" {
+		t.Fatalf("[entconv] expected a *ConversionError for a malformed id, got %v", err)
+	}
+	if _, err := entmap.ToEntDevice(&entpb.Device{Token: []byte{1, 2, 3}}); !errors.As(err, &convErr) || convErr.Field != "token" {
+		t.Fatalf("[entconv] expected a *ConversionError for a short token, got %v", err)
+	}
+}
+
+func TestEntconvTimeFormats(t *testing.T) {
+	created := time.UnixMilli(1_723_456_789_123)
+	expires := time.Date(2030, 1, 2, 3, 4, 5, 600_000_000, time.UTC)
+	seen := time.Date(2024, 8, 12, 9, 53, 9, 123_456_789, time.UTC)
+	entDevice := &ent.Device{
+		Name:       "phone",
+		CreatedAt:  created,
+		ExpiresAt:  expires,
+		LastSeenAt: &seen,
+	}
+	pbDevice, err := entmap.ToProtoDevice(entDevice)
+	if err != nil {
+		t.Fatalf("[entconv] ToProtoDevice failed: %v", err)
+	}
+	if pbDevice.CreatedAt != 1_723_456_789_123 || pbDevice.ExpiresAt != "2030-01-02T03:04:05.6Z" || !pbDevice.LastSeenAt.AsTime().Equal(seen) {
+		t.Fatalf("[entconv] unexpected pb device times: %v", pbDevice)
+	}
+	back, err := entmap.ToEntDevice(pbDevice)
+	if err != nil {
+		t.Fatalf("[entconv] ToEntDevice failed: %v", err)
+	}
+	if !back.CreatedAt.Equal(created) || !back.ExpiresAt.Equal(expires) || back.LastSeenAt == nil || !back.LastSeenAt.Equal(seen) {
+		t.Fatalf("[entconv] device times round-trip mismatch: %+v -> %+v", entDevice, back)
+	}
+
+	// Every format decodes to UTC, whatever the offset of the converted value.
+	if back.CreatedAt.Location() != time.UTC || back.ExpiresAt.Location() != time.UTC || back.LastSeenAt.Location() != time.UTC {
+		t.Fatalf("[entconv] decoded device times should be in UTC: %+v", back)
+	}
+	back, err = entmap.ToEntDevice(&entpb.Device{ExpiresAt: "2030-01-02T05:04:05.6+02:00"})
+	if err != nil || back.ExpiresAt.Location() != time.UTC || !back.ExpiresAt.Equal(expires) {
+		t.Fatalf("[entconv] ToEntDevice of an RFC 3339 offset=%v, %v", back.ExpiresAt, err)
+	}
+
+	// The zero time converts to the empty pb value, and a nil Timestamp to a
+	// nil Nillable field. "" converts back to the zero time, while the Unix
+	// time 0 converts back to the Unix epoch without WithUnixZeroTime.
+	epoch := time.Unix(0, 0).UTC()
+	zero, err := entmap.ToProtoDevice(&ent.Device{Name: "new"})
+	if err != nil || zero.CreatedAt != 0 || zero.ExpiresAt != "" || zero.LastSeenAt != nil {
+		t.Fatalf("[entconv] ToProtoDevice of zero times=%v, %v", zero, err)
+	}
+	back, err = entmap.ToEntDevice(zero)
+	if err != nil || !back.CreatedAt.Equal(epoch) || !back.ExpiresAt.IsZero() || back.LastSeenAt != nil {
+		t.Fatalf("[entconv] ToEntDevice of empty times=%+v, %v", back, err)
+	}
+
+	// The Unix epoch round-trips in every format.
+	pbEpoch, err := entmap.ToProtoDevice(&ent.Device{CreatedAt: epoch, ExpiresAt: epoch, LastSeenAt: &epoch})
+	if err != nil || pbEpoch.CreatedAt != 0 || pbEpoch.ExpiresAt != "1970-01-01T00:00:00Z" || pbEpoch.LastSeenAt == nil {
+		t.Fatalf("[entconv] ToProtoDevice of the Unix epoch=%v, %v", pbEpoch, err)
+	}
+	back, err = entmap.ToEntDevice(pbEpoch)
+	if err != nil || !back.CreatedAt.Equal(epoch) || !back.ExpiresAt.Equal(epoch) || back.LastSeenAt == nil || !back.LastSeenAt.Equal(epoch) {
+		t.Fatalf("[entconv] ToEntDevice of the Unix epoch=%+v, %v", back, err)
+	}
+
+	var convErr *entmap.ConversionError
+	if _, err := entmap.ToEntDevice(&entpb.Device{ExpiresAt: "tomorrow"}); !errors.As(err, &convErr) || convErr.Field != "expires_at" {
+		t.Fatalf("[entconv] expected a *ConversionError for a malformed expires_at, got %v", err)
+	}
+}
+
+func TestEntconvJSONEncodedFields(t *testing.T) {
+	entDevice := &ent.Device{
+		Name:     "phone",
+		Settings: schema.DeviceSettings{Theme: "dark", Notifications: true},
+		Labels:   map[string]string{"os": "android"},
+	}
+	pbDevice, err := entmap.ToProtoDevice(entDevice)
+	if err != nil {
+		t.Fatalf("[entconv] ToProtoDevice failed: %v", err)
+	}
+	if string(pbDevice.Settings) != `{"theme":"dark","notifications":true}` || pbDevice.Labels != `{"os":"android"}` {
+		t.Fatalf("[entconv] unexpected pb device JSON: settings=%s labels=%s", pbDevice.Settings, pbDevice.Labels)
+	}
+	back, err := entmap.ToEntDevice(pbDevice)
+	if err != nil {
+		t.Fatalf("[entconv] ToEntDevice failed: %v", err)
+	}
+	if back.Settings != entDevice.Settings || !maps.Equal(back.Labels, entDevice.Labels) {
+		t.Fatalf("[entconv] device JSON round-trip mismatch: %+v -> %+v", entDevice, back)
+	}
+
+	// Empty pb values decode to the zero value.
+	back, err = entmap.ToEntDevice(&entpb.Device{})
+	if err != nil || back.Settings != (schema.DeviceSettings{}) || back.Labels != nil {
+		t.Fatalf("[entconv] ToEntDevice of empty JSON=%+v, %v", back, err)
+	}
+
+	var convErr *entmap.ConversionError
+	if _, err := entmap.ToEntDevice(&entpb.Device{Settings: []byte("{")}); !errors.As(err, &convErr) || convErr.Field != "settings" {
+		t.Fatalf("[entconv] expected a *ConversionError for malformed settings, got %v", err)
+	}
+	if _, err := entmap.ToEntDevice(&entpb.Device{Labels: `["os"]`}); !errors.As(err, &convErr) || convErr.Field != "labels" {
+		t.Fatalf("[entconv] expected a *ConversionError for malformed labels, got %v", err)
+	}
+}
+
+func TestEntconvClonesMessageFields(t *testing.T) {
+	boot := timestamppb.New(time.Unix(1_723_456_789, 0))
+	entDevice := &ent.Device{
+		Name:              "phone",
+		HeartbeatInterval: durationpb.New(30 * time.Second),
+		BootTimes:         []*timestamppb.Timestamp{boot},
+	}
+	pbDevice, err := entmap.ToProtoDevice(entDevice)
+	if err != nil {
+		t.Fatalf("[entconv] ToProtoDevice failed: %v", err)
+	}
+	if !proto.Equal(pbDevice.HeartbeatInterval, entDevice.HeartbeatInterval) || len(pbDevice.BootTimes) != 1 || !proto.Equal(pbDevice.BootTimes[0], boot) {
+		t.Fatalf("[entconv] unexpected pb device messages: %v", pbDevice)
+	}
+	// Mutating the pb messages, e.g. in a response interceptor, leaves the entity untouched.
+	pbDevice.HeartbeatInterval.Seconds = 60
+	pbDevice.BootTimes[0].Seconds = 0
+	if entDevice.HeartbeatInterval.Seconds != 30 || boot.Seconds != 1_723_456_789 {
+		t.Fatalf("[entconv] ToProtoDevice should clone the message fields: %v", entDevice)
+	}
+
+	back, err := entmap.ToEntDevice(pbDevice)
+	if err != nil {
+		t.Fatalf("[entconv] ToEntDevice failed: %v", err)
+	}
+	pbDevice.HeartbeatInterval.Seconds = 90
+	pbDevice.BootTimes[0].Seconds = 1
+	if back.HeartbeatInterval.Seconds != 60 || back.BootTimes[0].Seconds != 0 {
+		t.Fatalf("[entconv] ToEntDevice should clone the message fields: %v", back)
+	}
+
+	empty, err := entmap.ToEntDevice(&entpb.Device{})
+	if err != nil || empty.HeartbeatInterval != nil || empty.BootTimes != nil {
+		t.Fatalf("[entconv] ToEntDevice of unset messages=%+v, %v", empty, err)
+	}
+}
+
+func equalPtr[T comparable](a, b *T) bool {
+	return a == nil && b == nil || a != nil && b != nil && *a == *b
+}